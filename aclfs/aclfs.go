@@ -0,0 +1,428 @@
+package aclfs
+
+import (
+	"context"
+	"io"
+	"os"
+	filepath "path"
+	"time"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// FS checks every operation on the wrapped filesystem and on the files
+// opened by it against an ordered list of rules. The first rule matching
+// the caller identity, the operation and the path decides, operations no
+// rule matches are denied.
+//
+// Rules match the paths with resolved symbolic links, so links can not be
+// used to circumvent them. This requires the wrapped filesystem to
+// implement vfs.ReadlinkFS if it contains symbolic links. The operations
+// are run on the resolved paths, which were checked. Renames and removals
+// of directories are checked for every path below them.
+//
+// Denied operations return an *os.PathError or *os.LinkError wrapping
+// a *DeniedError.
+type FS struct {
+	fs       vfs.Filesystem
+	rules    []Rule
+	identity string
+	audit    AuditFunc
+}
+
+// Create returns a filesystem checking every operation on fs against the rules.
+//
+//	fs := aclfs.Create(osfs,
+//		aclfs.Rule{Pattern: "/etc", Ops: aclfs.OpAll, Reason: "never touch /etc"},
+//		aclfs.Rule{Pattern: "/var/cache", Ops: aclfs.OpAll, Allow: true},
+//		aclfs.Rule{Pattern: "/", Ops: aclfs.OpReadOnly, Allow: true},
+//	)
+func Create(fs vfs.Filesystem, rules ...Rule) *FS {
+	return &FS{fs: fs, rules: append([]Rule(nil), rules...)}
+}
+
+// WithAudit sets a callback, which is called for every checked operation.
+func (fs *FS) WithAudit(fn AuditFunc) *FS {
+	fs.audit = fn
+	return fs
+}
+
+// WithContext implements vfs.FilesystemContext. It returns a copy of the
+// filesystem checking the operations for the caller identity carried by
+// ctx, see WithIdentity, and binds ctx to the wrapped filesystem.
+// Without identity only the rules for all callers apply.
+func (fs *FS) WithContext(ctx context.Context) vfs.Filesystem {
+	c := *fs
+	c.identity, _ = IdentityFrom(ctx)
+	c.fs = vfs.WithContext(ctx, fs.fs)
+	return &c
+}
+
+// decide evaluates the rules for a single operation on the resolved path.
+// It returns a *DeniedError if the operation is denied.
+func (fs *FS) decide(op Op, path string) error {
+	var rule *Rule
+	allowed := false
+	reason := "no rule allows the operation"
+	for i := range fs.rules {
+		r := &fs.rules[i]
+		ok, err := r.matches(fs.identity, op, path)
+		if err != nil {
+			rule, reason = r, "invalid pattern "+r.Pattern+": "+err.Error()
+			break
+		}
+		if ok {
+			rule, allowed, reason = r, r.Allow, r.Reason
+			break
+		}
+	}
+	if fs.audit != nil {
+		fs.audit(Event{Op: op, Path: path, Identity: fs.identity, Allowed: allowed, Rule: rule, Reason: reason})
+	}
+	if allowed {
+		return nil
+	}
+	return &DeniedError{Op: op, Path: path, Identity: fs.identity, Rule: rule, Reason: reason}
+}
+
+// decideAll evaluates the rules for every operation of ops.
+func (fs *FS) decideAll(ops Op, path string) error {
+	for op := Op(1); op <= ops; op <<= 1 {
+		if ops&op == 0 {
+			continue
+		}
+		if err := fs.decide(op, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the path of name with resolved symbolic links,
+// see vfs.ResolvePath.
+func (fs *FS) resolve(name string, follow bool) (string, error) {
+	return vfs.ResolvePath(fs.fs, name, follow)
+}
+
+// check evaluates the rules for ops on name. The error of a denied
+// operation is a *os.PathError.
+func (fs *FS) check(opName string, ops Op, name string, follow bool) (string, error) {
+	path, err := fs.resolve(name, follow)
+	if err != nil {
+		return "", err
+	}
+	if err := fs.decideAll(ops, path); err != nil {
+		return "", &os.PathError{Op: opName, Path: name, Err: err}
+	}
+	return path, nil
+}
+
+// PathSeparator implements vfs.Filesystem.
+func (fs *FS) PathSeparator() uint8 {
+	return fs.fs.PathSeparator()
+}
+
+// OpenFile implements vfs.Filesystem.
+// It checks OpRead and OpWrite according to flag and OpCreate
+// if os.O_CREATE creates a new file.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	path, err := fs.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	var ops Op
+	access := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	if access != os.O_WRONLY {
+		ops |= OpRead
+	}
+	if access != os.O_RDONLY || flag&(os.O_APPEND|os.O_TRUNC) != 0 {
+		ops |= OpWrite
+	}
+	if flag&os.O_CREATE != 0 {
+		if _, err := fs.fs.Stat(path); os.IsNotExist(err) {
+			ops |= OpCreate
+		}
+	}
+	if err := fs.decideAll(ops, path); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	f, err := fs.fs.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	seek := OpRead
+	if access == os.O_WRONLY {
+		seek = OpWrite
+	}
+	return &file{File: f, fs: fs, name: name, path: path, seek: seek}, nil
+}
+
+// Remove implements vfs.Filesystem and checks OpDelete.
+func (fs *FS) Remove(name string) error {
+	path, err := fs.check("remove", OpDelete, name, false)
+	if err != nil {
+		return err
+	}
+	return fs.fs.Remove(path)
+}
+
+// RemoveAll implements vfs.Filesystem and checks OpDelete on every removed path.
+// It stops at the first denied path.
+func (fs *FS) RemoveAll(name string) error {
+	path, err := fs.check("removeall", OpDelete, name, false)
+	if err != nil {
+		return err
+	}
+	fi, err := fs.fs.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.IsDir() {
+		fis, err := fs.fs.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, c := range fis {
+			if err := fs.RemoveAll(filepath.Join(path, c.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return fs.fs.Remove(path)
+}
+
+// Rename implements vfs.Filesystem and checks OpRename on both paths.
+// If oldpath is a directory, it checks OpRename on every path below it
+// and on the paths they are moved to. It stops at the first denied path.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	from, err := fs.resolve(oldpath, false)
+	if err != nil {
+		return err
+	}
+	to, err := fs.resolve(newpath, false)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkRename(from, to); err != nil {
+		if de, ok := err.(*DeniedError); ok {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: de}
+		}
+		return err
+	}
+	return fs.fs.Rename(from, to)
+}
+
+// checkRename evaluates the rules for OpRename on the resolved paths from
+// and to and on the paths below them, if from is a directory.
+// The error of a denied operation is a *DeniedError.
+func (fs *FS) checkRename(from, to string) error {
+	if err := fs.decide(OpRename, from); err != nil {
+		return err
+	}
+	if err := fs.decide(OpRename, to); err != nil {
+		return err
+	}
+	fi, err := fs.fs.Lstat(from)
+	if err != nil || !fi.IsDir() {
+		// Missing sources are reported by the rename
+		return nil
+	}
+	fis, err := fs.fs.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for _, c := range fis {
+		if err := fs.checkRename(filepath.Join(from, c.Name()), filepath.Join(to, c.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mkdir implements vfs.Filesystem and checks OpCreate.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	path, err := fs.check("mkdir", OpCreate, name, false)
+	if err != nil {
+		return err
+	}
+	return fs.fs.Mkdir(path, perm)
+}
+
+// MkdirAll implements vfs.Filesystem.
+// It checks OpStat on the existing and OpCreate on the created directories.
+func (fs *FS) MkdirAll(path string, perm os.FileMode) error {
+	return vfs.MkdirAll(fs, path, perm)
+}
+
+// Symlink implements vfs.Filesystem and checks OpCreate on newname.
+func (fs *FS) Symlink(oldname, newname string) error {
+	path, err := fs.check("symlink", OpCreate, newname, false)
+	if err != nil {
+		return err
+	}
+	return fs.fs.Symlink(oldname, path)
+}
+
+// Chmod implements vfs.ChmodFS and checks OpWrite.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	path, err := fs.check("chmod", OpWrite, name, true)
+	if err != nil {
+		return err
+	}
+	return vfs.Chmod(fs.fs, path, mode)
+}
+
+// Chtimes implements vfs.ChtimesFS and checks OpWrite.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	path, err := fs.check("chtimes", OpWrite, name, true)
+	if err != nil {
+		return err
+	}
+	return vfs.Chtimes(fs.fs, path, atime, mtime)
+}
+
+// Readlink implements vfs.ReadlinkFS and checks OpRead.
+func (fs *FS) Readlink(name string) (string, error) {
+	path, err := fs.check("readlink", OpRead, name, false)
+	if err != nil {
+		return "", err
+	}
+	return vfs.Readlink(fs.fs, path)
+}
+
+// Stat implements vfs.Filesystem and checks OpStat.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	path, err := fs.check("stat", OpStat, name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fs.fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if base := filepath.Base(name); fi.Name() != base {
+		// Stat reports the name of a symbolic link, not of its target
+		return namedInfo{FileInfo: fi, name: base}, nil
+	}
+	return fi, nil
+}
+
+// Lstat implements vfs.Filesystem and checks OpStat.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	path, err := fs.check("lstat", OpStat, name, false)
+	if err != nil {
+		return nil, err
+	}
+	return fs.fs.Lstat(path)
+}
+
+// ReadDir implements vfs.Filesystem and checks OpList.
+func (fs *FS) ReadDir(name string) ([]os.FileInfo, error) {
+	path, err := fs.check("readdir", OpList, name, true)
+	if err != nil {
+		return nil, err
+	}
+	return fs.fs.ReadDir(path)
+}
+
+// namedInfo is a FileInfo with a different name.
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi namedInfo) Name() string {
+	return fi.name
+}
+
+// file checks every read and write operation against the rules
+// of its filesystem on the resolved path it was opened with.
+type file struct {
+	vfs.File
+	fs   *FS
+	name string // name it was opened with
+	path string
+	seek Op // OpRead or OpWrite for write-only files
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+// check evaluates the rules for op on the file.
+func (f *file) check(opName string, op Op) error {
+	if err := f.fs.decide(op, f.path); err != nil {
+		return &os.PathError{Op: opName, Path: f.name, Err: err}
+	}
+	return nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if err := f.check("read", OpRead); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.check("read", OpRead); err != nil {
+		return 0, err
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if err := f.check("seek", f.seek); err != nil {
+		return 0, err
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if err := f.check("write", OpWrite); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.check("write", OpWrite); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, off)
+}
+
+func (f *file) Truncate(size int64) error {
+	if err := f.check("truncate", OpWrite); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
+
+// ReadFrom checks OpWrite and uses the io.ReaderFrom of the wrapped
+// file if available.
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	if err := f.check("write", OpWrite); err != nil {
+		return 0, err
+	}
+	if rf, ok := f.File.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(f.File, r)
+}
+
+// WriteTo checks OpRead and uses the io.WriterTo of the wrapped
+// file if available.
+func (f *file) WriteTo(w io.Writer) (int64, error) {
+	if err := f.check("read", OpRead); err != nil {
+		return 0, err
+	}
+	if wt, ok := f.File.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, f.File)
+}
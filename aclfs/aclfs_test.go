@@ -0,0 +1,287 @@
+package aclfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
+)
+
+func rootfs() vfs.Filesystem {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/etc", 0777)
+	vfs.MkdirAll(fs, "/var/cache", 0777)
+	vfs.MkdirAll(fs, "/home/alice/.ssh", 0777)
+	vfs.WriteFile(fs, "/etc/passwd", []byte("root"), 0666)
+	vfs.WriteFile(fs, "/var/cache/data", []byte("data"), 0666)
+	vfs.WriteFile(fs, "/home/alice/.ssh/id", []byte("key"), 0666)
+	return fs
+}
+
+var rules = []Rule{
+	{Pattern: "/etc", Ops: OpAll, Reason: "never touch /etc"},
+	{Pattern: "/home/*/.ssh", Ops: OpAll, Identity: "alice", Allow: true},
+	{Pattern: "/home/*/.ssh", Ops: OpAll, Reason: "private keys"},
+	{Pattern: "/var/cache", Ops: OpAll, Allow: true},
+	{Pattern: "/", Ops: OpReadOnly, Allow: true},
+}
+
+// denied checks that err is a permission error denied by a rule
+// with the given reason.
+func denied(t *testing.T, err error, reason string) {
+	t.Helper()
+	var de *DeniedError
+	if !errors.As(err, &de) || !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected denied error, got %v", err)
+		return
+	}
+	if de.Reason != reason {
+		t.Errorf("Unexpected reason %q, want %q", de.Reason, reason)
+	}
+}
+
+func TestRules(t *testing.T) {
+	fs := Create(rootfs(), rules...)
+
+	if data, err := vfs.ReadFile(fs, "/var/cache/data"); err != nil || string(data) != "data" {
+		t.Errorf("ReadFile: %q, %v", data, err)
+	}
+	if err := vfs.WriteFile(fs, "/var/cache/new", []byte("new"), 0666); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := fs.MkdirAll("/var/cache/a/b", 0777); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := fs.RemoveAll("/var/cache/a"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := fs.ReadDir("/"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	_, err := vfs.ReadFile(fs, "/etc/passwd")
+	denied(t, err, "never touch /etc")
+	_, err = fs.Stat("/etc")
+	denied(t, err, "never touch /etc")
+	_, err = vfs.ReadFile(fs, "/home/alice/.ssh/id")
+	denied(t, err, "private keys")
+
+	err = vfs.WriteFile(fs, "/new", nil, 0666)
+	denied(t, err, "no rule allows the operation")
+	if pe, ok := err.(*os.PathError); !ok || pe.Op != "open" || pe.Path != "/new" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	denied(t, fs.Mkdir("/dir", 0777), "no rule allows the operation")
+	denied(t, fs.Remove("/var"), "no rule allows the operation")
+	denied(t, fs.RemoveAll("/"), "no rule allows the operation")
+	denied(t, fs.Symlink("/etc/passwd", "/var/link"), "no rule allows the operation")
+	denied(t, vfs.Chmod(fs, "/etc/passwd", 0777), "never touch /etc")
+
+	err = fs.Rename("/var/cache/data", "/data")
+	denied(t, err, "no rule allows the operation")
+	if _, ok := err.(*os.LinkError); !ok {
+		t.Errorf("Expected *os.LinkError, got %#v", err)
+	}
+	if err := fs.Rename("/var/cache/data", "/var/cache/moved"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestRemoveAllProtected(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs,
+		Rule{Pattern: "/var/cache/keep", Ops: OpDelete, Reason: "keep"},
+		Rule{Pattern: "/var", Ops: OpAll, Allow: true},
+	)
+	vfs.WriteFile(rfs, "/var/cache/keep", nil, 0666)
+
+	denied(t, fs.RemoveAll("/var"), "keep")
+	if _, err := rfs.Stat("/var/cache/keep"); err != nil {
+		t.Errorf("Protected file removed: %s", err)
+	}
+}
+
+func TestRenameProtected(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs,
+		Rule{Pattern: "/data/secret", Ops: OpAll, Reason: "secret"},
+		Rule{Pattern: "/pub/hidden", Ops: OpAll, Reason: "hidden"},
+		Rule{Pattern: "/", Ops: OpAll, Allow: true},
+	)
+	vfs.MkdirAll(rfs, "/data/secret", 0777)
+	vfs.WriteFile(rfs, "/data/secret/key", []byte("key"), 0666)
+
+	_, err := vfs.ReadFile(fs, "/data/secret/key")
+	denied(t, err, "secret")
+	// The protected directory can not be moved by renaming its parent
+	err = fs.Rename("/data", "/pub")
+	denied(t, err, "secret")
+	if _, ok := err.(*os.LinkError); !ok {
+		t.Errorf("Expected *os.LinkError, got %#v", err)
+	}
+	if _, err := rfs.Stat("/data/secret/key"); err != nil {
+		t.Errorf("Protected file moved: %s", err)
+	}
+
+	// Nor can files be moved below a protected path
+	rfs.RemoveAll("/data/secret")
+	vfs.MkdirAll(rfs, "/data/hidden", 0777)
+	denied(t, fs.Rename("/data", "/pub"), "hidden")
+	rfs.Remove("/data/hidden")
+	if err := fs.Rename("/data", "/pub"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	fs := Create(rootfs(), rules...)
+
+	alice := fs.WithContext(WithIdentity(context.Background(), "alice"))
+	if data, err := vfs.ReadFile(alice, "/home/alice/.ssh/id"); err != nil || string(data) != "key" {
+		t.Errorf("ReadFile: %q, %v", data, err)
+	}
+	bob := fs.WithContext(WithIdentity(context.Background(), "bob"))
+	_, err := vfs.ReadFile(bob, "/home/alice/.ssh/id")
+	denied(t, err, "private keys")
+
+	var de *DeniedError
+	if errors.As(err, &de); de == nil || de.Identity != "bob" || !strings.Contains(err.Error(), "for bob") {
+		t.Errorf("Identity not reported: %v", err)
+	}
+	if id, ok := IdentityFrom(context.Background()); ok || id != "" {
+		t.Errorf("Unexpected identity %q", id)
+	}
+
+	// The context is bound to the wrapped filesystem
+	ctx, cancel := context.WithCancel(WithIdentity(context.Background(), "alice"))
+	cancel()
+	if _, err := fs.WithContext(ctx).Stat("/var"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, rules...)
+	if err := rfs.Symlink("/etc", "/var/cache/etc"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	_, err := vfs.ReadFile(fs, "/var/cache/etc/passwd")
+	denied(t, err, "never touch /etc")
+	_, err = vfs.ReadFile(fs, "/var/cache/../../etc/passwd")
+	denied(t, err, "never touch /etc")
+	// The link itself is inside /var/cache
+	if err := fs.Remove("/var/cache/etc"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestFileOperations(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, Rule{Pattern: "/var/cache/data", Ops: OpAll, Allow: true})
+
+	f, err := fs.OpenFile("/var/cache/data", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+
+	// Rules are evaluated on every operation
+	fs.rules[0].Allow = false
+	buf := make([]byte, 4)
+	if _, err := f.Read(buf); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+	if _, err := f.Write(buf); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+	if err := f.Truncate(0); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+	if f.Name() != "/var/cache/data" {
+		t.Errorf("Unexpected name %q", f.Name())
+	}
+}
+
+func TestSeekWriteOnly(t *testing.T) {
+	fs := Create(rootfs(), Rule{Pattern: "/var/cache", Ops: OpWrite | OpStat, Allow: true})
+
+	f, err := fs.OpenFile("/var/cache/data", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(2, io.SeekStart); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+}
+
+func TestResolvedPaths(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, rules...)
+	if err := rfs.Symlink("/var/cache", "/cache"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	// The operations run on the checked paths
+	if err := vfs.WriteFile(fs, "/cache/new", []byte("new"), 0666); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if data, err := vfs.ReadFile(rfs, "/var/cache/new"); err != nil || string(data) != "new" {
+		t.Errorf("ReadFile: %q, %v", data, err)
+	}
+	if fi, err := fs.Stat("/cache"); err != nil || fi.Name() != "cache" || !fi.IsDir() {
+		t.Errorf("Stat = %v, %v", fi, err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var events []Event
+	fs := Create(rootfs(), rules...).WithAudit(func(e Event) {
+		events = append(events, e)
+	})
+
+	fs.Stat("/var/cache/data")
+	fs.Remove("/etc/passwd")
+	fs.Stat("/home/alice/.ssh/x[")
+
+	if len(events) != 3 {
+		t.Fatalf("Unexpected events: %v", events)
+	}
+	if e := events[0]; !e.Allowed || e.Op != OpStat || e.Path != "/var/cache/data" || e.Rule != &fs.rules[3] {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+	if e := events[1]; e.Allowed || e.Op != OpDelete || e.Reason != "never touch /etc" {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+	if e := events[2]; e.Allowed || e.Rule != &fs.rules[2] {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+}
+
+func TestBadPattern(t *testing.T) {
+	fs := Create(rootfs(), Rule{Pattern: "/[", Ops: OpAll, Allow: true})
+	_, err := fs.Stat("/var")
+	var de *DeniedError
+	if !errors.As(err, &de) || !strings.Contains(de.Reason, "invalid pattern") {
+		t.Errorf("Expected invalid pattern, got %v", err)
+	}
+}
+
+func TestOpString(t *testing.T) {
+	if s := (OpRead | OpWrite).String(); s != "read|write" {
+		t.Errorf("Unexpected string %q", s)
+	}
+	if s := Op(0).String(); s != "none" {
+		t.Errorf("Unexpected string %q", s)
+	}
+}
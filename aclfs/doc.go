@@ -0,0 +1,3 @@
+// Package aclfs defines a filesystem wrapper, which checks every operation
+// against an ordered list of access rules.
+package aclfs
@@ -0,0 +1,42 @@
+package aclfs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/aclfs"
+	"github.com/lordofscripts/vfs/memfs"
+)
+
+func ExampleFS() {
+	mfs := memfs.Create()
+	vfs.MkdirAll(mfs, "/var/cache", 0777)
+	vfs.MkdirAll(mfs, "/etc", 0777)
+
+	// Read anywhere, write only under /var/cache, never touch /etc
+	fs := aclfs.Create(mfs,
+		aclfs.Rule{Pattern: "/etc", Ops: aclfs.OpAll, Reason: "never touch /etc"},
+		aclfs.Rule{Pattern: "/var/cache", Ops: aclfs.OpAll, Allow: true},
+		aclfs.Rule{Pattern: "/", Ops: aclfs.OpReadOnly, Allow: true},
+	).WithAudit(func(e aclfs.Event) {
+		if !e.Allowed {
+			fmt.Printf("audit: %s %s by %q denied\n", e.Op, e.Path, e.Identity)
+		}
+	})
+
+	// Check the operations for the caller in the context
+	ctx := aclfs.WithIdentity(context.Background(), "www")
+	userFS := fs.WithContext(ctx)
+
+	fmt.Println(vfs.WriteFile(userFS, "/var/cache/page", []byte("cached"), 0666))
+	err := vfs.WriteFile(userFS, "/etc/passwd", nil, 0666)
+	fmt.Println(err, errors.Is(err, os.ErrPermission))
+
+	// Output:
+	// <nil>
+	// audit: write /etc/passwd by "www" denied
+	// open /etc/passwd: write denied on /etc/passwd for www: never touch /etc true
+}
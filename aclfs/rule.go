@@ -0,0 +1,153 @@
+package aclfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	filepath "path"
+	"strings"
+)
+
+// Op is a set of operations controlled by a Rule.
+type Op uint
+
+const (
+	// OpRead reads files and symbolic links: OpenFile for reading, Read,
+	// ReadAt, WriteTo, Readlink and Seek on files opened for reading.
+	OpRead Op = 1 << iota
+
+	// OpWrite modifies existing files: OpenFile for writing, Write, WriteAt,
+	// ReadFrom, Truncate, Chmod, Chtimes and Seek on files opened only
+	// for writing.
+	OpWrite
+
+	// OpCreate creates files by OpenFile, Mkdir, MkdirAll and Symlink.
+	OpCreate
+
+	// OpDelete removes files by Remove and RemoveAll.
+	OpDelete
+
+	// OpRename renames files, it is checked for both paths and the paths
+	// below them, if a directory is renamed.
+	OpRename
+
+	// OpStat reads file information by Stat and Lstat.
+	OpStat
+
+	// OpList lists directories by ReadDir.
+	OpList
+
+	// OpAll contains all operations.
+	OpAll = OpRead | OpWrite | OpCreate | OpDelete | OpRename | OpStat | OpList
+
+	// OpReadOnly contains the operations, which do not modify the filesystem.
+	OpReadOnly = OpRead | OpStat | OpList
+)
+
+var opNames = []string{"read", "write", "create", "delete", "rename", "stat", "list"}
+
+// String returns the names of the operations separated by "|".
+func (op Op) String() string {
+	var names []string
+	for i, name := range opNames {
+		if op&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// Rule allows or denies operations on the paths matching Pattern.
+type Rule struct {
+	// Pattern is an absolute path or a glob of path.Match, like "/etc" or
+	// "/home/*/.ssh". It matches the paths it describes and all paths
+	// below them, so "/" matches every path.
+	Pattern string
+
+	// Ops are the operations controlled by the rule.
+	Ops Op
+
+	// Allow allows the operations, otherwise they are denied.
+	Allow bool
+
+	// Identity restricts the rule to the callers with this identity,
+	// see WithIdentity. The rule applies to all callers if it is empty.
+	Identity string
+
+	// Reason describes the rule in errors and audit events.
+	Reason string
+}
+
+// matches reports whether the rule applies to op on the clean path
+// for the caller identity. A malformed pattern results in an error.
+func (r *Rule) matches(identity string, op Op, path string) (bool, error) {
+	if r.Ops&op == 0 || (r.Identity != "" && r.Identity != identity) {
+		return false, nil
+	}
+	for {
+		ok, err := filepath.Match(r.Pattern, path)
+		if ok || err != nil {
+			return ok, err
+		}
+		if path == "/" {
+			return false, nil
+		}
+		path = filepath.Dir(path)
+	}
+}
+
+// DeniedError reports an operation denied by a Rule.
+// It is wrapped by *os.PathError and *os.LinkError
+// and matches os.ErrPermission by errors.Is.
+type DeniedError struct {
+	Op       Op
+	Path     string
+	Identity string
+	Rule     *Rule // nil if no rule matched
+	Reason   string
+}
+
+func (e *DeniedError) Error() string {
+	msg := fmt.Sprintf("%s denied on %s", e.Op, e.Path)
+	if e.Identity != "" {
+		msg += " for " + e.Identity
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *DeniedError) Unwrap() error {
+	return os.ErrPermission
+}
+
+// Event describes a checked operation for the audit callback.
+type Event struct {
+	Op       Op
+	Path     string
+	Identity string
+	Allowed  bool
+	Rule     *Rule // nil if no rule matched
+	Reason   string
+}
+
+// AuditFunc is called on every checked operation.
+type AuditFunc func(Event)
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity of the caller,
+// which is used by FS.WithContext to select the rules.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the identity of the caller carried by ctx.
+func IdentityFrom(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
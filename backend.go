@@ -0,0 +1,74 @@
+package vfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// BackendFactory creates a filesystem of a backend from a source, whose
+// meaning depends on the backend, and backend specific options.
+// It returns the filesystem and the directory of it to use, "/" for the
+// whole filesystem. Unknown options should be rejected with an error.
+type BackendFactory func(source string, options map[string]string) (fs Filesystem, root string, err error)
+
+var (
+	backendsMutex sync.RWMutex
+	backends      = make(map[string]BackendFactory)
+)
+
+func init() {
+	RegisterBackend("os", func(source string, options map[string]string) (Filesystem, string, error) {
+		if len(options) > 0 {
+			return nil, "", fmt.Errorf("os: unsupported options %v", options)
+		}
+		if source == "" {
+			source = "/"
+		}
+		if !filepath.IsAbs(source) {
+			return nil, "", fmt.Errorf("os: source %q is not an absolute path", source)
+		}
+		return OS(), source, nil
+	})
+}
+
+// RegisterBackend makes a backend available by the provided name,
+// e.g. for mount table configurations. Packages providing a filesystem
+// register their backends in their init function.
+// If RegisterBackend is called twice with the same name or if factory
+// is nil, it panics.
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+
+	if factory == nil {
+		panic("vfs: RegisterBackend factory is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("vfs: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// LookupBackend returns the factory of the backend registered by name.
+func LookupBackend(name string) (BackendFactory, bool) {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	factory, ok := backends[name]
+	return factory, ok
+}
+
+// Backends returns a sorted list of the names of the registered backends.
+func Backends() []string {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,45 @@
+package vfs
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestRegisterBackend(t *testing.T) {
+	factory := func(source string, options map[string]string) (Filesystem, string, error) {
+		return Dummy(nil), "/", nil
+	}
+	RegisterBackend("test", factory)
+	if _, ok := LookupBackend("test"); !ok {
+		t.Errorf("Backend not registered")
+	}
+	if _, ok := LookupBackend("missing"); ok {
+		t.Errorf("Unexpected backend")
+	}
+	if names := Backends(); !sort.StringsAreSorted(names) || !slices.Contains(names, "os") || !slices.Contains(names, "test") {
+		t.Errorf("Unexpected backends: %v", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic on duplicate backend")
+		}
+	}()
+	RegisterBackend("test", factory)
+}
+
+func TestOSBackend(t *testing.T) {
+	factory, _ := LookupBackend("os")
+	if fs, root, err := factory("", nil); err != nil || root != "/" {
+		t.Errorf("Unexpected backend: %v %q %v", fs, root, err)
+	} else if _, ok := fs.(*OsFS); !ok {
+		t.Errorf("Unexpected backend: %v %q %v", fs, root, err)
+	}
+	if _, _, err := factory("/srv", map[string]string{"ro": ""}); err == nil {
+		t.Errorf("Expected error on unknown option")
+	}
+	if _, _, err := factory("relative/dir", nil); err == nil {
+		t.Errorf("Expected error on relative source")
+	}
+}
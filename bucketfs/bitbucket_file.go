@@ -154,6 +154,19 @@ func (f BitBucketFile) ReadAt(p []byte, offset int64) (n int, err error) {
 	return n, f.err
 }
 
+// WriteAt augments the size of the file if the write ends beyond it
+// but does not store any content.
+// Errors: none
+func (f BitBucketFile) WriteAt(p []byte, offset int64) (n int, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if end := offset + int64(len(p)); end > f.size {
+		f.size = end
+	}
+	return len(p), nil
+}
+
 // Seek advances the fake file pointer.
 // Errors. ErrSeek or the error given to the hybrid BitBucketFS constructor.
 func (f BitBucketFile) Seek(offset int64, whence int) (int64, error) {
@@ -62,6 +62,7 @@ type BitBucketFS struct {
 	silent  bool  // simply print operation & param and produces no error
 	err     error // if not nil, same error for all entry points
 	entries map[string]iBucketNode
+	dev     uint64 // device number reported by vfs.SysInfo
 }
 
 /* ----------------------------------------------------------------
@@ -72,7 +73,7 @@ type BitBucketFS struct {
 // on the console. No error is produced.
 // @implements vfs.Filesystem
 func Create() *BitBucketFS {
-	return &BitBucketFS{&sync.RWMutex{}, true, nil, make(map[string]iBucketNode, 0)}
+	return &BitBucketFS{&sync.RWMutex{}, true, nil, make(map[string]iBucketNode, 0), vfs.NewDev()}
 }
 
 // CreateWithError is the constructor for hybrid mode. Alternatively use the
@@ -84,7 +85,7 @@ func CreateWithError(err error) *BitBucketFS {
 	if err == nil {
 		panic("BitBucketFS ctor. needs err parameter")
 	}
-	return &BitBucketFS{&sync.RWMutex{}, false, err, make(map[string]iBucketNode, 0)}
+	return &BitBucketFS{&sync.RWMutex{}, false, err, make(map[string]iBucketNode, 0), vfs.NewDev()}
 }
 
 /* ----------------------------------------------------------------
@@ -377,6 +378,12 @@ func (bfs *BitBucketFS) internalStat(operation, name string) (os.FileInfo, error
 		} else {
 			finfo = newBitBucketFileInfo(name, node.Size(), node.Mode())
 		}
+		finfo.ISys = &vfs.SysInfo{
+			Dev:   bfs.dev,
+			Ino:   node.Ino(),
+			Nlink: 1,
+			Mtime: finfo.IModTime,
+		}
 	} else {
 		finfo.IName = name
 	}
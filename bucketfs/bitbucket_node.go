@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"github.com/lordofscripts/vfs"
 )
@@ -26,6 +27,9 @@ const (
 	ALL_RW_PERMS os.FileMode = 0666
 )
 
+// lastIno is the last inode number given to a node
+var lastIno atomic.Uint64
+
 /* ----------------------------------------------------------------
  *						I n t e r f a c e s
  *-----------------------------------------------------------------*/
@@ -61,6 +65,8 @@ type iBucketNode interface {
 	ClientData() any
 	// Stringer shows Perms() as string. For symbolic links also what it points to.
 	String() string
+	// unique inode number of the node
+	Ino() uint64
 }
 
 /* ----------------------------------------------------------------
@@ -75,6 +81,7 @@ type bucketNodeLite struct {
 	isLink bool
 	target string
 	extra  any
+	ino    uint64
 }
 
 // BucketNode is a slightly more functional (yet minimal) node information
@@ -85,6 +92,7 @@ type bucketNode struct {
 	fsize  int64
 	target string
 	extra  any
+	ino    uint64
 }
 
 /* ----------------------------------------------------------------
@@ -95,12 +103,12 @@ type bucketNode struct {
 func newBucketNodeLite(mode os.FileMode) *bucketNodeLite {
 	return &bucketNodeLite{vfs.HasFileModeFlag(os.ModeDir, mode),
 		vfs.HasFileModeFlag(os.ModeSymlink, mode),
-		"", nil}
+		"", nil, lastIno.Add(1)}
 }
 
 // newBucketNodeLite is a more functional version of a BitBucketFS node
 func newBucketNode(mode os.FileMode) *bucketNode {
-	return &bucketNode{mode, 0, "", nil}
+	return &bucketNode{mode, 0, "", nil, lastIno.Add(1)}
 }
 
 // createNode creates a BitBucketFS node. The developer chooses to select
@@ -173,6 +181,10 @@ func (min *bucketNodeLite) ClientData() any {
 	return min.extra
 }
 
+func (min *bucketNodeLite) Ino() uint64 {
+	return min.ino
+}
+
 func (min *bucketNodeLite) String() string {
 	var mode os.FileMode
 	if min.isDir {
@@ -236,6 +248,10 @@ func (b *bucketNode) ClientData() any {
 	return b.extra
 }
 
+func (b *bucketNode) Ino() uint64 {
+	return b.ino
+}
+
 func (b *bucketNode) String() string {
 	targetStr := ""
 	if b.IsLink() && len(b.target) > 0 {
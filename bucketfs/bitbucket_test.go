@@ -438,6 +438,41 @@ func Test_BitBucketFS_Lstat(t *testing.T) {
 	outcome(ok)
 }
 
+/* ----------------------------------------------------------------
+ *	vfs.SameFile()
+ *-----------------------------------------------------------------*/
+func Test_BitBucketFS_SameFile(t *testing.T) {
+	const Oper = "SameFile"
+	fmt.Printf(cCASE_TITLE_TEMPLATE, Oper)
+
+	ok := true
+	fs := CreateWithError(ErrAny).
+		WithFakeFiles([]string{cFAKE_FILE1, cFAKE_FILE2})
+	if err := fs.Symlink(cFAKE_FILE1, cFILE1); err != nil {
+		t.Fatalf("Symlink nil expected: %s", err)
+	}
+
+	fi, err := fs.Stat(cFAKE_FILE1)
+	if err != nil {
+		t.Fatalf("Stat nil expected: %s", err)
+	}
+	if sys := vfs.SysInfoOf(fi); sys == nil || sys.Ino == 0 || sys.Dev == 0 {
+		t.Errorf("%s SysInfo expected: %v", Oper, fi.Sys())
+		ok = false
+	}
+	// a symbolic link is followed
+	if same, err := vfs.SameFile(fs, cFAKE_FILE1, cFILE1); err != nil || !same {
+		t.Errorf("%s true expected: %v %v", Oper, same, err)
+		ok = false
+	}
+	if same, err := vfs.SameFile(fs, cFAKE_FILE1, cFAKE_FILE2); err != nil || same {
+		t.Errorf("%s false expected: %v %v", Oper, same, err)
+		ok = false
+	}
+
+	outcome(ok)
+}
+
 /* ----------------------------------------------------------------
  *	Filesystem.Stat()
  *-----------------------------------------------------------------*/
@@ -0,0 +1,299 @@
+package vfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FilesystemContext is implemented by filesystems, which bind a context to
+// their operations themselves, e.g. wrappers binding it to the filesystems
+// they wrap, so cancellation reaches the filesystem doing the work.
+type FilesystemContext interface {
+	Filesystem
+
+	// WithContext returns a view of the filesystem, whose operations
+	// fail with the error of ctx once it is done.
+	WithContext(ctx context.Context) Filesystem
+}
+
+// WithContext returns a view of fs, whose operations and files fail with
+// the error of ctx once it is done. Long running operations like RemoveAll
+// check ctx between their steps. Close is never cancelled.
+//
+// If fs implements FilesystemContext, its WithContext is used.
+// If there is an error, it will be of type *os.PathError or *os.LinkError
+// wrapping ctx.Err().
+func WithContext(ctx context.Context, fs Filesystem) Filesystem {
+	if cfs, ok := fs.(FilesystemContext); ok {
+		return cfs.WithContext(ctx)
+	}
+	return &ctxFS{ctx: ctx, fs: fs}
+}
+
+// WalkContext walks the file tree rooted at root like Walk and stops with
+// the error of ctx once it is done.
+func WalkContext(ctx context.Context, fs Filesystem, root string, walkFunc filepath.WalkFunc) error {
+	return Walk(WithContext(ctx, fs), root, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return walkFunc(path, info, err)
+	})
+}
+
+// RemoveAllContext removes path and any children it contains like RemoveAll
+// and stops with the error of ctx once it is done.
+func RemoveAllContext(ctx context.Context, fs Filesystem, path string) error {
+	return WithContext(ctx, fs).RemoveAll(path)
+}
+
+// ReadFileContext reads the file named by filename like ReadFile
+// and stops with the error of ctx once it is done.
+func ReadFileContext(ctx context.Context, fs Filesystem, filename string) ([]byte, error) {
+	return ReadFile(WithContext(ctx, fs), filename)
+}
+
+// copyBufferSize is the size of the chunks copied by CopyContext.
+const copyBufferSize = 32 * 1024
+
+// CopyContext copies from src to dst until either EOF is reached on src or
+// an error occurs like io.Copy. It checks ctx between the copied chunks
+// and stops with its error once it is done.
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader) (written int64, err error) {
+	buf := make([]byte, copyBufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		nr, er := src.Read(buf)
+		if nr > 0 {
+			nw, ew := dst.Write(buf[0:nr])
+			if nw < 0 || nr < nw {
+				nw = 0
+				if ew == nil {
+					ew = io.ErrShortWrite
+				}
+			}
+			written += int64(nw)
+			if ew != nil {
+				return written, ew
+			}
+			if nr != nw {
+				return written, io.ErrShortWrite
+			}
+		}
+		if er != nil {
+			if er == io.EOF {
+				return written, nil
+			}
+			return written, er
+		}
+	}
+}
+
+// ctxFS checks a context before every operation on a filesystem.
+type ctxFS struct {
+	ctx context.Context
+	fs  Filesystem
+}
+
+// check returns a *os.PathError wrapping the error of the context once it is done.
+func (fs *ctxFS) check(op, path string) error {
+	if err := fs.ctx.Err(); err != nil {
+		return &os.PathError{Op: op, Path: path, Err: err}
+	}
+	return nil
+}
+
+func (fs *ctxFS) PathSeparator() uint8 {
+	return fs.fs.PathSeparator()
+}
+
+func (fs *ctxFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if err := fs.check("open", name); err != nil {
+		return nil, err
+	}
+	f, err := fs.fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &ctxFile{File: f, ctx: fs.ctx}, nil
+}
+
+func (fs *ctxFS) Remove(name string) error {
+	if err := fs.check("remove", name); err != nil {
+		return err
+	}
+	return fs.fs.Remove(name)
+}
+
+// RemoveAll removes path and its children one by one, depth first,
+// so it can be cancelled between them.
+func (fs *ctxFS) RemoveAll(path string) error {
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.IsDir() {
+		fis, err := fs.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		sep := string(fs.PathSeparator())
+		for _, c := range fis {
+			if err := fs.RemoveAll(strings.TrimSuffix(path, sep) + sep + c.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (fs *ctxFS) Rename(oldpath, newpath string) error {
+	if err := fs.ctx.Err(); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return fs.fs.Rename(oldpath, newpath)
+}
+
+func (fs *ctxFS) Mkdir(name string, perm os.FileMode) error {
+	if err := fs.check("mkdir", name); err != nil {
+		return err
+	}
+	return fs.fs.Mkdir(name, perm)
+}
+
+func (fs *ctxFS) MkdirAll(path string, perm os.FileMode) error {
+	return MkdirAll(fs, path, perm)
+}
+
+func (fs *ctxFS) Symlink(oldname, newname string) error {
+	if err := fs.check("symlink", newname); err != nil {
+		return err
+	}
+	return fs.fs.Symlink(oldname, newname)
+}
+
+func (fs *ctxFS) Readlink(name string) (string, error) {
+	if err := fs.check("readlink", name); err != nil {
+		return "", err
+	}
+	return Readlink(fs.fs, name)
+}
+
+func (fs *ctxFS) Chmod(name string, mode os.FileMode) error {
+	if err := fs.check("chmod", name); err != nil {
+		return err
+	}
+	return Chmod(fs.fs, name, mode)
+}
+
+func (fs *ctxFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := fs.check("chtimes", name); err != nil {
+		return err
+	}
+	return Chtimes(fs.fs, name, atime, mtime)
+}
+
+func (fs *ctxFS) Stat(name string) (os.FileInfo, error) {
+	if err := fs.check("stat", name); err != nil {
+		return nil, err
+	}
+	return fs.fs.Stat(name)
+}
+
+func (fs *ctxFS) Lstat(name string) (os.FileInfo, error) {
+	if err := fs.check("lstat", name); err != nil {
+		return nil, err
+	}
+	return fs.fs.Lstat(name)
+}
+
+func (fs *ctxFS) ReadDir(path string) ([]os.FileInfo, error) {
+	if err := fs.check("readdir", path); err != nil {
+		return nil, err
+	}
+	return fs.fs.ReadDir(path)
+}
+
+// ctxFile checks a context before every operation on a file but Close.
+type ctxFile struct {
+	File
+	ctx context.Context
+}
+
+func (f *ctxFile) check(op string) error {
+	if err := f.ctx.Err(); err != nil {
+		return &os.PathError{Op: op, Path: f.File.Name(), Err: err}
+	}
+	return nil
+}
+
+func (f *ctxFile) Read(p []byte) (int, error) {
+	if err := f.check("read"); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+func (f *ctxFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.check("read"); err != nil {
+		return 0, err
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *ctxFile) Write(p []byte) (int, error) {
+	if err := f.check("write"); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *ctxFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.check("write"); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, off)
+}
+
+func (f *ctxFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.check("seek"); err != nil {
+		return 0, err
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *ctxFile) Truncate(size int64) error {
+	if err := f.check("truncate"); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
+
+func (f *ctxFile) Sync() error {
+	if err := f.check("sync"); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+// ReadFrom copies from r in chunks and stops once the context is done.
+func (f *ctxFile) ReadFrom(r io.Reader) (int64, error) {
+	return CopyContext(f.ctx, f.File, r)
+}
+
+// WriteTo copies to w in chunks and stops once the context is done.
+func (f *ctxFile) WriteTo(w io.Writer) (int64, error) {
+	return CopyContext(f.ctx, w, f.File)
+}
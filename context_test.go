@@ -0,0 +1,147 @@
+package vfs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
+	"github.com/lordofscripts/vfs/mountfs"
+	"github.com/lordofscripts/vfs/prefixfs"
+)
+
+// cancelFS cancels a context after a number of removes.
+type cancelFS struct {
+	vfs.Filesystem
+	cancel  context.CancelFunc
+	removes int
+}
+
+func (fs *cancelFS) Remove(name string) error {
+	if fs.removes--; fs.removes == 0 {
+		fs.cancel()
+	}
+	return fs.Filesystem.Remove(name)
+}
+
+func manyFiles(t *testing.T, fs vfs.Filesystem, dir string, n int) {
+	if err := vfs.MkdirAll(fs, dir, 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	for i := 0; i < n; i++ {
+		if err := vfs.WriteFile(fs, dir+"/file"+strconv.Itoa(i), []byte("data"), 0666); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+}
+
+func TestWithContextCancelled(t *testing.T) {
+	fs := memfs.Create()
+	manyFiles(t, fs, "/dir", 1)
+	f, err := fs.OpenFile("/dir/file0", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cfs := vfs.WithContext(ctx, fs)
+	cf, err := cfs.OpenFile("/dir/file0", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	cancel()
+
+	if _, err := cfs.Stat("/dir"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if err := cfs.Rename("/dir", "/other"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := vfs.ReadFileContext(ctx, fs, "/dir/file0"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := cf.Read(make([]byte, 4)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := cf.Write([]byte("x")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if err := cf.Close(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestReadFileContext(t *testing.T) {
+	fs := memfs.Create()
+	manyFiles(t, fs, "/dir", 1)
+	data, err := vfs.ReadFileContext(context.Background(), fs, "/dir/file0")
+	if err != nil || string(data) != "data" {
+		t.Errorf("ReadFileContext: %q, %v", data, err)
+	}
+}
+
+func TestWalkContext(t *testing.T) {
+	fs := memfs.Create()
+	manyFiles(t, fs, "/dir", 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	visited := 0
+	err := vfs.WalkContext(ctx, fs, "/", func(path string, info os.FileInfo, err error) error {
+		if visited++; visited == 3 {
+			cancel()
+		}
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if visited != 3 {
+		t.Errorf("Walk not stopped: %d visits", visited)
+	}
+}
+
+func TestRemoveAllContextPropagation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mfs := memfs.Create()
+	manyFiles(t, mfs, "/p/dir", 10)
+
+	// The context is bound through the MountFS, RoFS options and the prefixfs
+	leaf := &cancelFS{Filesystem: mfs, cancel: cancel, removes: 3}
+	fs := mountfs.Create(memfs.Create())
+	fs.Mount(prefixfs.Create(leaf, "/p"), "/m")
+	fs.MountWithOptions(memfs.Create(), "/ro", mountfs.MountOptions{ReadOnly: true})
+
+	err := vfs.RemoveAllContext(ctx, fs, "/m/dir")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	fis, err := mfs.ReadDir("/p/dir")
+	if err != nil || len(fis) != 7 {
+		t.Errorf("RemoveAll not stopped: %d files left, %v", len(fis), err)
+	}
+	if _, err := vfs.WithContext(ctx, fs).Stat("/ro"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCopyContext(t *testing.T) {
+	src := strings.Repeat("x", 100*1024)
+	var dst bytes.Buffer
+	n, err := vfs.CopyContext(context.Background(), &dst, strings.NewReader(src))
+	if err != nil || n != int64(len(src)) || dst.String() != src {
+		t.Errorf("CopyContext: %d, %v", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := vfs.CopyContext(ctx, &dst, strings.NewReader(src)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
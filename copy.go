@@ -0,0 +1,572 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ExistPolicy decides how existing files are handled by copies.
+type ExistPolicy int
+
+const (
+	// ExistFail fails with an *os.PathError wrapping os.ErrExist.
+	ExistFail ExistPolicy = iota
+
+	// ExistOverwrite replaces existing files and symbolic links.
+	ExistOverwrite
+
+	// ExistSkip keeps existing files and symbolic links.
+	ExistSkip
+
+	// ExistUpdate replaces existing files and symbolic links, which were
+	// modified before the source.
+	ExistUpdate
+)
+
+// CopyOptions configure CopyFile, CopyTree and Move.
+type CopyOptions struct {
+	// Exist decides how existing files and symbolic links are handled.
+	// Existing directories are merged.
+	Exist ExistPolicy
+
+	// PreserveMode sets the modes of the copies to the modes of the sources
+	// including the setuid, setgid and sticky bits. Otherwise the copies get
+	// the permissions of the sources, subject to the umask of the destination.
+	PreserveMode bool
+
+	// PreserveTimes sets the access and modification times of the copies
+	// to the times of the sources.
+	PreserveTimes bool
+
+	// FollowSymlinks copies the targets of symbolic links instead of the
+	// links like cp -L. A link leading to a directory being copied fails
+	// with an error wrapping ErrFilesystemLoop.
+	FollowSymlinks bool
+
+	// Progress is called with the paths of every copied file, directory and
+	// symbolic link and the number of bytes copied. It is called
+	// concurrently if Workers is greater than 1.
+	Progress func(src, dst string, written int64)
+
+	// Workers is the maximum number of files copied concurrently.
+	// Files are copied one by one if it is less than 2.
+	Workers int
+}
+
+// copyModeBits are the bits of a mode preserved by copies.
+const copyModeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// CopyFile copies the file or symbolic link src of srcFS to dst on dstFS
+// according to the options. Directories fail with syscall.EISDIR.
+func CopyFile(srcFS Filesystem, src string, dstFS Filesystem, dst string, opts CopyOptions) error {
+	fi, err := newCopier(srcFS, dstFS, opts, false).stat(src)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &os.PathError{Op: "copy", Path: src, Err: syscall.EISDIR}
+	}
+	return CopyTree(srcFS, src, dstFS, dst, opts)
+}
+
+// CopyTree copies the file, directory tree or symbolic link src of srcFS
+// to dst on dstFS according to the options. The files are copied before
+// the modes and times of their directories are set. Copying stops at the
+// first error, the files copied so far are kept. Copying a directory
+// into itself fails with syscall.EINVAL, also if the destination reaches
+// the source through another view of the same files, like a bind mount.
+// Directories are identified like by WalkOptions.FollowSymlinks.
+func CopyTree(srcFS Filesystem, src string, dstFS Filesystem, dst string, opts CopyOptions) error {
+	return newCopier(srcFS, dstFS, opts, false).run(src, dst)
+}
+
+// Move moves the file, directory tree or symbolic link src of srcFS to dst
+// on dstFS. If both are views of the same files, src is renamed, unless dst
+// is an existing directory to merge into. Otherwise, or if renaming fails
+// with syscall.EXDEV, src is copied like by CopyTree preserving modes and
+// times. Once the whole tree is copied, the copied files and the emptied
+// directories are removed, so src is left unchanged if the copy fails and
+// the move can be retried. Skipped files are kept in src.
+func Move(srcFS Filesystem, src string, dstFS Filesystem, dst string, opts CopyOptions) error {
+	if renamable(srcFS, dstFS, dst) {
+		done, err := moveRename(srcFS, src, dst, opts)
+		if done || err != nil && !errors.Is(err, syscall.EXDEV) {
+			return err
+		}
+	}
+	opts.PreserveMode, opts.PreserveTimes = true, true
+	return newCopier(srcFS, dstFS, opts, true).run(src, dst)
+}
+
+// sameFilesystem reports whether a and b are the same filesystem.
+func sameFilesystem(a, b Filesystem) bool {
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() && a == b
+}
+
+// renamable reports whether srcFS can rename its files to dst on dstFS,
+// i.e. whether both are the same filesystem or srcFS sees the directory
+// of dst as the same directory as dstFS, like views of the same files.
+func renamable(srcFS, dstFS Filesystem, dst string) bool {
+	if sameFilesystem(srcFS, dstFS) {
+		return true
+	}
+	if srcFS.PathSeparator() != dstFS.PathSeparator() {
+		return false
+	}
+	dir := parentDir(dstFS, dst)
+	sfi, err := srcFS.Stat(dir)
+	if err != nil {
+		return false
+	}
+	dfi, err := dstFS.Stat(dir)
+	if err != nil {
+		return false
+	}
+	s, d := SysInfoOf(sfi), SysInfoOf(dfi)
+	return s != nil && d != nil && s.Ino != 0 && s.Dev == d.Dev && s.Ino == d.Ino
+}
+
+// parentDir returns the directory containing name on fs.
+func parentDir(fs Filesystem, name string) string {
+	sep := string(fs.PathSeparator())
+	switch i := strings.LastIndex(strings.TrimSuffix(name, sep), sep); i {
+	case -1:
+		return "."
+	case 0:
+		return sep
+	default:
+		return name[:i]
+	}
+}
+
+// moveRename moves src to dst on fs by renaming it according to the
+// options and reports whether it is done. Directories are not merged.
+func moveRename(fs Filesystem, src, dst string, opts CopyOptions) (bool, error) {
+	fi, err := fs.Lstat(src)
+	if err != nil {
+		return true, err
+	}
+	if dfi, err := fs.Lstat(dst); err == nil {
+		if fi.IsDir() && dfi.IsDir() {
+			return false, nil
+		}
+		switch opts.Exist {
+		case ExistFail:
+			return true, &os.LinkError{Op: "move", Old: src, New: dst, Err: os.ErrExist}
+		case ExistSkip:
+			return true, nil
+		case ExistUpdate:
+			if !fi.ModTime().After(dfi.ModTime()) {
+				return true, nil
+			}
+		}
+	}
+	if err := fs.Rename(src, dst); err != nil {
+		return false, err
+	}
+	if opts.Progress != nil {
+		opts.Progress(src, dst, 0)
+	}
+	return true, nil
+}
+
+// Preserve sets the mode and the access and modification times of fi on
+// the file name, unless the filesystem does not support it.
+func Preserve(fs Filesystem, name string, fi os.FileInfo) error {
+	if err := preserveMode(fs, name, fi.Mode()&copyModeBits); err != nil {
+		return err
+	}
+	return preserveTimes(fs, name, fi)
+}
+
+// preserveMode sets the mode of name, unless the filesystem does not support it.
+func preserveMode(fs Filesystem, name string, mode os.FileMode) error {
+	if err := Chmod(fs, name, mode); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// preserveTimes sets the times of fi on name, unless the filesystem does
+// not support it.
+func preserveTimes(fs Filesystem, name string, fi os.FileInfo) error {
+	atime := fi.ModTime()
+	if sys := SysInfoOf(fi); sys != nil && !sys.Atime.IsZero() {
+		atime = sys.Atime
+	}
+	if err := Chtimes(fs, name, atime, fi.ModTime()); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// copier holds the state of a copy.
+type copier struct {
+	srcFS, dstFS Filesystem
+	opts         CopyOptions
+	move         bool        // remove the copied sources
+	cfg          *walkConfig // identifies the directories to detect loops
+
+	// created are the identities of the directories created or merged
+	// into on dstFS, which must not be copied themselves.
+	// It is only used by the goroutine walking the source.
+	created map[fileID]bool
+
+	jobs chan copyJob // files to copy by the workers
+	wg   sync.WaitGroup
+
+	mu    sync.Mutex
+	err   error
+	dirs  []copyJob // copied directories in post-order
+	moved []string  // copied files and symbolic links to remove by a move
+}
+
+// copyJob is a file or directory to copy.
+type copyJob struct {
+	src, dst string
+	fi       os.FileInfo
+	created  bool // the directory was created
+}
+
+func newCopier(srcFS, dstFS Filesystem, opts CopyOptions, move bool) *copier {
+	return &copier{
+		srcFS: srcFS,
+		dstFS: dstFS,
+		opts:  opts,
+		move:  move,
+		cfg:   &walkConfig{fs: srcFS, opts: WalkOptions{FollowSymlinks: true}},
+
+		created: map[fileID]bool{},
+	}
+}
+
+// fail records the first error.
+func (c *copier) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *copier) failed() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *copier) stat(name string) (os.FileInfo, error) {
+	if c.opts.FollowSymlinks {
+		return c.srcFS.Stat(name)
+	}
+	return c.srcFS.Lstat(name)
+}
+
+func (c *copier) progress(src, dst string, written int64) {
+	if c.opts.Progress != nil {
+		c.opts.Progress(src, dst, written)
+	}
+}
+
+// run copies src to dst, finishes the copied directories and removes
+// the copied sources of a move.
+func (c *copier) run(src, dst string) error {
+	if c.inside(src, dst) {
+		return &os.PathError{Op: "copy", Path: dst, Err: syscall.EINVAL}
+	}
+	if c.opts.Workers > 1 {
+		c.jobs = make(chan copyJob)
+		for i := 0; i < c.opts.Workers; i++ {
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				for job := range c.jobs {
+					if c.failed() != nil {
+						continue
+					}
+					if err := c.copyFile(job.src, job.dst, job.fi); err != nil {
+						c.fail(err)
+					}
+				}
+			}()
+		}
+	}
+	c.copy(src, dst, nil)
+	if c.jobs != nil {
+		close(c.jobs)
+		c.wg.Wait()
+	}
+	if err := c.failed(); err != nil {
+		return err
+	}
+
+	for _, d := range c.dirs {
+		if err := c.finishDir(d); err != nil {
+			return err
+		}
+	}
+	if c.move {
+		return c.removeSources()
+	}
+	return nil
+}
+
+// removeSources removes the copied files and symbolic links of a move
+// and the directories, which are empty afterwards.
+func (c *copier) removeSources() error {
+	for _, name := range c.moved {
+		if err := c.srcFS.Remove(name); err != nil {
+			return err
+		}
+	}
+	for _, d := range c.dirs {
+		if fis, err := c.srcFS.ReadDir(d.src); err == nil && len(fis) == 0 {
+			if err := c.srcFS.Remove(d.src); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dirID returns the identity of the directory name with the FileInfo fi
+// on fs, which is either the source or the destination filesystem.
+// It reports false if the directory can not be compared with the
+// directories of the other filesystem.
+func (c *copier) dirID(fs Filesystem, name string, fi os.FileInfo) (fileID, bool) {
+	if sys := SysInfoOf(fi); sys != nil && sys.Ino != 0 {
+		return fileID{dev: sys.Dev, ino: sys.Ino}, true
+	}
+	if !sameFilesystem(c.srcFS, c.dstFS) {
+		return fileID{}, false
+	}
+	resolved, err := ResolvePath(fs, name, true)
+	if err != nil {
+		return fileID{}, false
+	}
+	return fileID{path: resolved}, true
+}
+
+// inside reports whether dst is src or a path below it.
+func (c *copier) inside(src, dst string) bool {
+	fi, err := c.stat(src)
+	if err != nil {
+		return false
+	}
+	id, ok := c.dirID(c.srcFS, src, fi)
+	if !ok {
+		return false
+	}
+	// Start at the existing directories to resolve ".." and symbolic links
+	name, err := ResolvePath(c.dstFS, dst, true)
+	if err != nil {
+		return false
+	}
+	for {
+		if dfi, err := c.dstFS.Stat(name); err == nil {
+			if did, ok := c.dirID(c.dstFS, name, dfi); ok && did == id {
+				return true
+			}
+		}
+		parent := parentDir(c.dstFS, name)
+		if parent == name || parent == "." {
+			return false
+		}
+		name = parent
+	}
+}
+
+// copy copies src to dst. ancestors are the identities of the directories
+// of src being copied if symbolic links are followed.
+func (c *copier) copy(src, dst string, ancestors []fileID) {
+	if c.failed() != nil {
+		return
+	}
+	fi, err := c.stat(src)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	switch {
+	case fi.IsDir():
+		err = c.copyDir(src, dst, fi, ancestors)
+	case fi.Mode()&os.ModeSymlink != 0:
+		err = c.copyLink(src, dst, fi)
+	case c.jobs != nil:
+		c.jobs <- copyJob{src: src, dst: dst, fi: fi}
+	default:
+		err = c.copyFile(src, dst, fi)
+	}
+	if err != nil {
+		c.fail(err)
+	}
+}
+
+// prepare checks the destination of a copy according to the ExistPolicy and
+// removes a file or symbolic link to replace. It reports whether dst is
+// a directory to merge into or the copy is skipped.
+func (c *copier) prepare(dst string, fi os.FileInfo) (merge, skip bool, err error) {
+	dfi, err := c.dstFS.Lstat(dst)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if fi.IsDir() && dfi.IsDir() {
+		return true, false, nil
+	}
+	switch c.opts.Exist {
+	case ExistFail:
+		return false, false, &os.PathError{Op: "copy", Path: dst, Err: os.ErrExist}
+	case ExistSkip:
+		return false, true, nil
+	case ExistUpdate:
+		if !fi.ModTime().After(dfi.ModTime()) {
+			return false, true, nil
+		}
+	}
+	if dfi.IsDir() {
+		return false, false, &os.PathError{Op: "copy", Path: dst, Err: syscall.EISDIR}
+	}
+	return false, false, c.dstFS.Remove(dst)
+}
+
+// copyFile copies the content of the file src to dst.
+func (c *copier) copyFile(src, dst string, fi os.FileInfo) error {
+	if _, skip, err := c.prepare(dst, fi); skip || err != nil {
+		return err
+	}
+
+	in, err := c.srcFS.OpenFile(src, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Keep the file writable until its content is copied
+	perm := fi.Mode().Perm()
+	out, err := c.dstFS.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm|0600)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := c.finish(dst, fi, perm != perm|0600); err != nil {
+		return err
+	}
+	c.progress(src, dst, written)
+	c.moveSource(src)
+	return nil
+}
+
+// moveSource records a copied file or symbolic link to remove by a move.
+func (c *copier) moveSource(src string) {
+	if c.move {
+		c.mu.Lock()
+		c.moved = append(c.moved, src)
+		c.mu.Unlock()
+	}
+}
+
+// copyLink copies the symbolic link src to dst.
+func (c *copier) copyLink(src, dst string, fi os.FileInfo) error {
+	if _, skip, err := c.prepare(dst, fi); skip || err != nil {
+		return err
+	}
+	target, err := Readlink(c.srcFS, src)
+	if err != nil {
+		return err
+	}
+	if err := c.dstFS.Symlink(target, dst); err != nil {
+		return err
+	}
+	c.progress(src, dst, 0)
+	c.moveSource(src)
+	return nil
+}
+
+// copyDir creates or merges the directory dst and copies the entries
+// of src. The directory is finished by run.
+func (c *copier) copyDir(src, dst string, fi os.FileInfo, ancestors []fileID) error {
+	if c.opts.FollowSymlinks {
+		id := c.cfg.dirID(src, fi)
+		if slices.Contains(ancestors, id) {
+			return &os.PathError{Op: "copy", Path: src, Err: ErrFilesystemLoop}
+		}
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], id)
+	}
+	if id, ok := c.dirID(c.srcFS, src, fi); ok && c.created[id] {
+		// The copy reached its destination, e.g. through a bind mount
+		return &os.PathError{Op: "copy", Path: src, Err: syscall.EINVAL}
+	}
+
+	merge, skip, err := c.prepare(dst, fi)
+	if skip || err != nil {
+		return err
+	}
+	if !merge {
+		// Keep the directory writable until its content is copied
+		if err := c.dstFS.Mkdir(dst, fi.Mode().Perm()|0700); err != nil {
+			return err
+		}
+	}
+	if dfi, err := c.dstFS.Stat(dst); err == nil {
+		if id, ok := c.dirID(c.dstFS, dst, dfi); ok {
+			c.created[id] = true
+		}
+	}
+	fis, err := c.srcFS.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, child := range fis {
+		c.copy(joinPath(c.srcFS, src, child.Name()), joinPath(c.dstFS, dst, child.Name()), ancestors)
+	}
+
+	c.mu.Lock()
+	c.dirs = append(c.dirs, copyJob{src: src, dst: dst, fi: fi, created: !merge})
+	c.mu.Unlock()
+	return nil
+}
+
+// finishDir sets the mode and times of a copied directory.
+func (c *copier) finishDir(d copyJob) error {
+	perm := d.fi.Mode().Perm()
+	if err := c.finish(d.dst, d.fi, d.created && perm != perm|0700); err != nil {
+		return err
+	}
+	c.progress(d.src, d.dst, 0)
+	return nil
+}
+
+// finish sets the mode and times of the copy name of fi according to the
+// options. restore sets the permissions of fi without preserving the mode.
+func (c *copier) finish(name string, fi os.FileInfo, restore bool) error {
+	switch {
+	case c.opts.PreserveMode:
+		if err := preserveMode(c.dstFS, name, fi.Mode()&copyModeBits); err != nil {
+			return err
+		}
+	case restore:
+		if err := preserveMode(c.dstFS, name, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if c.opts.PreserveTimes {
+		return preserveTimes(c.dstFS, name, fi)
+	}
+	return nil
+}
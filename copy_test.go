@@ -0,0 +1,346 @@
+package vfs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
+	"github.com/lordofscripts/vfs/mountfs"
+)
+
+var oldTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// makeCopyTree creates a tree with files, a read-only directory
+// and a symbolic link below root.
+func makeCopyTree(t *testing.T, fs vfs.Filesystem, root string) {
+	if err := vfs.MkdirAll(fs, root+"/dir/ro", 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	files := map[string]os.FileMode{"/file": 0640, "/dir/exe": 0755, "/dir/ro/data": 0444}
+	for name, perm := range files {
+		if err := vfs.WriteFile(fs, root+name, []byte("content of "+name), perm); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		if err := vfs.Chmod(fs, root+name, perm); err != nil {
+			t.Fatalf("Chmod: %s", err)
+		}
+	}
+	if err := fs.Symlink("../file", root+"/dir/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	if err := vfs.Chtimes(fs, root+"/file", oldTime, oldTime); err != nil {
+		t.Fatalf("Chtimes: %s", err)
+	}
+	if err := vfs.Chmod(fs, root+"/dir/ro", 0555); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+}
+
+func checkContent(t *testing.T, fs vfs.Filesystem, name, want string) {
+	t.Helper()
+	if data, err := vfs.ReadFile(fs, name); err != nil || string(data) != want {
+		t.Errorf("ReadFile(%s) = %q, %v, want %q", name, data, err, want)
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	td := t.TempDir()
+	osfs := vfs.OS()
+	makeCopyTree(t, osfs, td+"/src")
+	t.Cleanup(func() { vfs.Chmod(osfs, td+"/src/dir/ro", 0755) })
+
+	fs := memfs.Create()
+	var copied []string
+	err := vfs.CopyTree(osfs, td+"/src", fs, "/dst", vfs.CopyOptions{
+		PreserveMode:  true,
+		PreserveTimes: true,
+		Progress: func(src, dst string, written int64) {
+			copied = append(copied, dst)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, name := range []string{"/file", "/dir/exe", "/dir/ro/data"} {
+		checkContent(t, fs, "/dst"+name, "content of "+name)
+	}
+	if target, err := vfs.Readlink(fs, "/dst/dir/link"); err != nil || target != "../file" {
+		t.Errorf("Readlink = %q, %v", target, err)
+	}
+	for name, perm := range map[string]os.FileMode{"/file": 0640, "/dir/exe": 0755, "/dir/ro": 0555, "/dir/ro/data": 0444} {
+		if fi, err := fs.Stat("/dst" + name); err != nil || fi.Mode().Perm() != perm {
+			t.Errorf("Stat(%s) = %v, %v, want mode %v", name, fi, err, perm)
+		}
+	}
+	if fi, _ := fs.Stat("/dst/file"); !fi.ModTime().Equal(oldTime) {
+		t.Errorf("Modification time not preserved: %s", fi.ModTime())
+	}
+	if len(copied) != 7 || copied[len(copied)-1] != "/dst" {
+		t.Errorf("Unexpected progress: %q", copied)
+	}
+
+	// Without preserving the times, nor following links
+	if err := vfs.CopyTree(osfs, td+"/src", fs, "/plain", vfs.CopyOptions{FollowSymlinks: true}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if fi, _ := fs.Stat("/plain/file"); fi.ModTime().Equal(oldTime) {
+		t.Errorf("Modification time preserved")
+	}
+	if fi, err := fs.Lstat("/plain/dir/link"); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("Link not followed: %v, %v", fi, err)
+	}
+	checkContent(t, fs, "/plain/dir/link", "content of /file")
+	if fi, _ := fs.Stat("/plain/dir/ro"); fi.Mode().Perm() != 0555 {
+		t.Errorf("Unexpected mode %v", fi.Mode())
+	}
+
+	if err := vfs.CopyTree(fs, "/dst", fs, "/dst/dir/copy", vfs.CopyOptions{}); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Expected invalid argument error, got %v", err)
+	}
+}
+
+func TestCopyExist(t *testing.T) {
+	fs := memfs.Create()
+	makeCopyTree(t, fs, "/src")
+	reset := func() {
+		fs.RemoveAll("/dst")
+		vfs.MkdirAll(fs, "/dst/dir", 0777)
+		vfs.WriteFile(fs, "/dst/file", []byte("old"), 0666)
+		vfs.WriteFile(fs, "/dst/dir/exe", []byte("new"), 0666)
+		vfs.Chtimes(fs, "/dst/file", oldTime.Add(-time.Hour), oldTime.Add(-time.Hour))
+	}
+
+	reset()
+	if err := vfs.CopyTree(fs, "/src", fs, "/dst", vfs.CopyOptions{}); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+
+	reset()
+	if err := vfs.CopyTree(fs, "/src", fs, "/dst", vfs.CopyOptions{Exist: vfs.ExistSkip}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkContent(t, fs, "/dst/file", "old")
+	checkContent(t, fs, "/dst/dir/exe", "new")
+	checkContent(t, fs, "/dst/dir/ro/data", "content of /dir/ro/data")
+
+	reset()
+	if err := vfs.CopyTree(fs, "/src", fs, "/dst", vfs.CopyOptions{Exist: vfs.ExistUpdate}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkContent(t, fs, "/dst/file", "content of /file")
+	checkContent(t, fs, "/dst/dir/exe", "new")
+
+	reset()
+	if err := vfs.CopyTree(fs, "/src", fs, "/dst", vfs.CopyOptions{Exist: vfs.ExistOverwrite}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkContent(t, fs, "/dst/file", "content of /file")
+	checkContent(t, fs, "/dst/dir/exe", "content of /dir/exe")
+
+	// Directories are never replaced by files
+	vfs.MkdirAll(fs, "/dst/dirfile", 0777)
+	if err := vfs.CopyFile(fs, "/src/file", fs, "/dst/dirfile", vfs.CopyOptions{Exist: vfs.ExistOverwrite}); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("Expected is a directory error, got %v", err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	fs := memfs.Create()
+	makeCopyTree(t, fs, "/src")
+	if err := vfs.CopyFile(fs, "/src/file", fs, "/copy", vfs.CopyOptions{}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkContent(t, fs, "/copy", "content of /file")
+	if err := vfs.CopyFile(fs, "/src/dir", fs, "/copydir", vfs.CopyOptions{}); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("Expected is a directory error, got %v", err)
+	}
+	if err := vfs.CopyFile(fs, "/src/missing", fs, "/copy2", vfs.CopyOptions{}); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestCopyTreeLoop(t *testing.T) {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/src/dir", 0777)
+	fs.Symlink("/src", "/src/dir/up")
+	err := vfs.CopyTree(fs, "/src", fs, "/dst", vfs.CopyOptions{FollowSymlinks: true})
+	if !errors.Is(err, vfs.ErrFilesystemLoop) {
+		t.Errorf("Expected loop error, got %v", err)
+	}
+}
+
+func TestCopyTreeIntoItself(t *testing.T) {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/a/b", 0777)
+	vfs.WriteFile(fs, "/a/b/file", []byte("data"), 0666)
+	view := vfs.WithContext(context.Background(), fs)
+	bound := mountfs.Create(memfs.Create())
+	vfs.MkdirAll(bound, "/a", 0777)
+	if err := bound.Bind(fs, "/a", "/a"); err != nil {
+		t.Fatalf("Bind: %s", err)
+	}
+
+	for _, test := range []struct {
+		srcFS vfs.Filesystem
+		src   string
+		dstFS vfs.Filesystem
+		dst   string
+	}{
+		{view, "/a", fs, "/a/c"},
+		{fs, "/a", fs, "/x/../a/c"},
+		{fs, "/a", bound, "/a/b/c"},
+		{bound, "/a", fs, "/a/c"},
+	} {
+		err := vfs.CopyTree(test.srcFS, test.src, test.dstFS, test.dst, vfs.CopyOptions{})
+		if !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("CopyTree(%s, %s): Expected invalid argument error, got %v", test.src, test.dst, err)
+		}
+	}
+
+	// Reaching the destination by following a link
+	fs.Symlink("/dst", "/a/link")
+	err := vfs.CopyTree(fs, "/a", fs, "/dst", vfs.CopyOptions{FollowSymlinks: true})
+	if !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Expected invalid argument error, got %v", err)
+	}
+}
+
+func TestCopyTreeWorkers(t *testing.T) {
+	fs := memfs.Create()
+	manyFiles(t, fs, "/src/a", 20)
+	manyFiles(t, fs, "/src/b/c", 20)
+
+	var files atomic.Int64
+	var bytes atomic.Int64
+	err := vfs.CopyTree(fs, "/src", fs, "/dst", vfs.CopyOptions{
+		Workers: 4,
+		Progress: func(src, dst string, written int64) {
+			files.Add(1)
+			bytes.Add(written)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if files.Load() != 44 || bytes.Load() != 40*4 {
+		t.Errorf("Unexpected progress: %d files, %d bytes", files.Load(), bytes.Load())
+	}
+	for i := 0; i < 20; i++ {
+		checkContent(t, fs, "/dst/b/c/file"+strconv.Itoa(i), "data")
+	}
+
+	// Errors stop the copy
+	vfs.MkdirAll(fs, "/exists/a", 0777)
+	vfs.WriteFile(fs, "/exists/a/file7", nil, 0666)
+	err = vfs.CopyTree(fs, "/src", fs, "/exists", vfs.CopyOptions{Workers: 4})
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	fs := memfs.Create()
+	makeCopyTree(t, fs, "/src")
+
+	// Renamed on the same filesystem
+	if err := vfs.Move(fs, "/src/file", fs, "/moved", vfs.CopyOptions{}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkContent(t, fs, "/moved", "content of /file")
+	if _, err := fs.Lstat("/src/file"); !os.IsNotExist(err) {
+		t.Errorf("Source not removed: %v", err)
+	}
+	if err := vfs.Move(fs, "/src/dir/exe", fs, "/moved", vfs.CopyOptions{}); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+
+	// Copied to another filesystem
+	other := memfs.Create()
+	vfs.MkdirAll(other, "/dst/dir", 0777)
+	vfs.WriteFile(other, "/dst/dir/exe", []byte("kept"), 0666)
+	if err := vfs.Move(fs, "/src", other, "/dst", vfs.CopyOptions{Exist: vfs.ExistSkip}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkContent(t, other, "/dst/dir/exe", "kept")
+	checkContent(t, other, "/dst/dir/ro/data", "content of /dir/ro/data")
+	if fi, err := other.Stat("/dst/dir/ro"); err != nil || fi.Mode().Perm() != 0555 {
+		t.Errorf("Mode not preserved: %v, %v", fi, err)
+	}
+	var left []string
+	vfs.Walk(fs, "/src", func(path string, info os.FileInfo, err error) error {
+		left = append(left, path)
+		return err
+	})
+	if want := []string{"/src", "/src/dir", "/src/dir/exe"}; !reflect.DeepEqual(left, want) {
+		t.Errorf("Left %q, want the skipped file %q", left, want)
+	}
+}
+
+func TestMoveAcrossMounts(t *testing.T) {
+	fs := mountfs.Create(memfs.Create())
+	data := memfs.Create()
+	vfs.MkdirAll(fs, "/data", 0777)
+	if err := fs.Mount(data, "/data"); err != nil {
+		t.Fatalf("Mount: %s", err)
+	}
+	makeCopyTree(t, fs, "/src")
+	if err := fs.Rename("/src", "/data/src"); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("Expected cross-device error, got %v", err)
+	}
+
+	if err := vfs.Move(fs, "/src", fs, "/data/src", vfs.CopyOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	checkContent(t, data, "/src/dir/ro/data", "content of /dir/ro/data")
+	if _, err := fs.Lstat("/src"); !os.IsNotExist(err) {
+		t.Errorf("Source not removed: %v", err)
+	}
+}
+
+func TestMoveView(t *testing.T) {
+	fs := memfs.Create()
+	makeCopyTree(t, fs, "/src")
+	before, _ := fs.Stat("/src")
+
+	// Views of the same files rename
+	view := vfs.WithContext(context.Background(), fs)
+	if err := vfs.Move(view, "/src", fs, "/moved", vfs.CopyOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if after, err := fs.Stat("/moved"); err != nil || vfs.SysInfoOf(after).Ino != vfs.SysInfoOf(before).Ino {
+		t.Errorf("Not renamed: %v, %v", after, err)
+	}
+}
+
+func TestMoveFailure(t *testing.T) {
+	fs := memfs.Create()
+	makeCopyTree(t, fs, "/src")
+	other := memfs.Create()
+	vfs.MkdirAll(other, "/dst/dir/ro", 0777)
+	vfs.WriteFile(other, "/dst/dir/ro/data", []byte("exists"), 0666)
+
+	// The sources are kept, if the copy fails
+	if err := vfs.Move(fs, "/src", other, "/dst", vfs.CopyOptions{}); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	for _, name := range []string{"/file", "/dir/exe", "/dir/ro/data"} {
+		checkContent(t, fs, "/src"+name, "content of "+name)
+	}
+
+	// And the move can be retried
+	if err := vfs.Move(fs, "/src", other, "/dst", vfs.CopyOptions{Exist: vfs.ExistOverwrite}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	checkContent(t, other, "/dst/dir/ro/data", "content of /dir/ro/data")
+	if _, err := fs.Lstat("/src"); !os.IsNotExist(err) {
+		t.Errorf("Source not removed: %v", err)
+	}
+}
@@ -57,6 +57,9 @@ func TestDummyFile(t *testing.T) {
 	if _, err := f.Read([]byte{}); err != errDum {
 		t.Errorf("Read DummyError expected: %s", err)
 	}
+	if _, err := f.WriteAt([]byte("test"), 0); err != errDum {
+		t.Errorf("WriteAt DummyError expected: %s", err)
+	}
 	if _, err := f.Seek(0, 0); err != errDum {
 		t.Errorf("Seek DummyError expected: %s", err)
 	}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,10 +39,68 @@ type Filesystem interface {
 	ReadDir(path string) ([]os.FileInfo, error)
 }
 
+// ReadlinkFS is a Filesystem supporting to read the destination
+// of symbolic links.
+type ReadlinkFS interface {
+	Filesystem
+	Readlink(name string) (string, error)
+}
+
+// Readlink returns the destination of the named symbolic link
+// on the given Filesystem.
+// If the Filesystem does not implement ReadlinkFS, it fails
+// with errors.ErrUnsupported.
+// If there is an error, it will be of type *os.PathError.
+func Readlink(fs Filesystem, name string) (string, error) {
+	if rfs, ok := fs.(ReadlinkFS); ok {
+		return rfs.Readlink(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+}
+
+// ChmodFS is a Filesystem supporting to change the mode of files.
+type ChmodFS interface {
+	Filesystem
+	Chmod(name string, mode os.FileMode) error
+}
+
+// Chmod changes the mode of the named file on the given Filesystem.
+// If the Filesystem does not implement ChmodFS, it fails
+// with errors.ErrUnsupported.
+// If there is an error, it will be of type *os.PathError.
+func Chmod(fs Filesystem, name string, mode os.FileMode) error {
+	if cfs, ok := fs.(ChmodFS); ok {
+		return cfs.Chmod(name, mode)
+	}
+	return &os.PathError{Op: "chmod", Path: name, Err: errors.ErrUnsupported}
+}
+
+// ChtimesFS is a Filesystem supporting to change the access and
+// modification times of files.
+type ChtimesFS interface {
+	Filesystem
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// Chtimes changes the access and modification times of the named file
+// on the given Filesystem, like os.Chtimes.
+// If the Filesystem does not implement ChtimesFS, it fails
+// with errors.ErrUnsupported.
+// If there is an error, it will be of type *os.PathError.
+func Chtimes(fs Filesystem, name string, atime time.Time, mtime time.Time) error {
+	if cfs, ok := fs.(ChtimesFS); ok {
+		return cfs.Chtimes(name, atime, mtime)
+	}
+	return &os.PathError{Op: "chtimes", Path: name, Err: errors.ErrUnsupported}
+}
+
 // File represents a File with common operations.
 // It differs from os.File so e.g. Stat() needs to be called from the Filesystem instead.
 //
 //	osfile.Stat() -> filesystem.Stat(file.Name())
+//
+// Like os.File, a File may implement io.ReaderFrom and io.WriterTo to
+// speed up io.Copy. Wrappers of a File should forward these methods.
 type File interface {
 	Name() string
 	Sync() error
@@ -52,6 +111,7 @@ type File interface {
 	io.Reader
 	io.ReaderAt
 	io.Writer
+	io.WriterAt
 	io.Seeker
 	io.Closer
 }
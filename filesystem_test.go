@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 )
 
 type openFS struct {
@@ -277,3 +278,33 @@ func TestRemoveAll(t *testing.T) {
 		t.Errorf("/ was removed")
 	}
 }
+
+type readlinkFS struct {
+	Filesystem
+}
+
+func (fs readlinkFS) Readlink(name string) (string, error) {
+	return "target", nil
+}
+
+func TestReadlink(t *testing.T) {
+	if target, err := Readlink(readlinkFS{Dummy(nil)}, "link"); err != nil || target != "target" {
+		t.Errorf("Unexpected target: %q %v", target, err)
+	}
+	_, err := Readlink(Dummy(nil), "link")
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported: %v", err)
+	}
+	if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Expected *os.PathError: %T", err)
+	}
+}
+
+func TestChmodChtimesUnsupported(t *testing.T) {
+	if err := Chmod(Dummy(nil), "file", 0644); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported: %v", err)
+	}
+	if err := Chtimes(Dummy(nil), "file", time.Now(), time.Now()); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileType is a set of file types selected by Query.Type.
+type FileType int
+
+// The file types of Query.Type.
+const (
+	Regular   FileType = 1 << iota // regular files
+	Directory                      // directories
+	Symlink                        // symbolic links
+	Irregular                      // devices, pipes, sockets, ...
+)
+
+// typeOf returns the FileType of a file.
+func typeOf(info os.FileInfo) FileType {
+	switch mode := info.Mode(); {
+	case info.IsDir():
+		return Directory
+	case mode.IsRegular():
+		return Regular
+	case mode&os.ModeSymlink != 0:
+		return Symlink
+	}
+	return Irregular
+}
+
+// Query finds the files of a tree matching all its predicates, like find.
+// The predicates are added by the methods returning the Query:
+//
+//	for path, info := range vfs.Find(fs, "/src").Name("*.go").Type(vfs.Regular).Size(">1MB").All() {
+//		...
+//	}
+//
+// The depth, Path and Prune predicates prune the traversal, so the
+// directories they exclude are not read. A Query is not safe for
+// concurrent use.
+type Query struct {
+	fs    Filesystem
+	root  string
+	opts  WalkOptions
+	preds []func(path string, info os.FileInfo) bool
+
+	minDepth, maxDepth int
+	paths              [][][]string // alternatives of the Path patterns in segments
+	prune              []string
+
+	err  error // invalid predicate
+	errs []error
+}
+
+// Find returns a Query finding all files in the tree rooted at root.
+func Find(fs Filesystem, root string) *Query {
+	return &Query{fs: fs, root: root, maxDepth: -1}
+}
+
+// filter adds a predicate.
+func (q *Query) filter(pred func(path string, info os.FileInfo) bool) *Query {
+	q.preds = append(q.preds, pred)
+	return q
+}
+
+// invalid records the first invalid predicate.
+func (q *Query) invalid(err error) *Query {
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+// Name selects the files whose base name matches any of the patterns of
+// path.Match.
+func (q *Query) Name(patterns ...string) *Query {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return q.invalid(fmt.Errorf("find: name %q: %w", pattern, err))
+		}
+	}
+	return q.filter(func(_ string, info os.FileInfo) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, info.Name()); ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Path selects the files whose path relative to the root matches any of the
+// patterns of DoublestarMatch, like "src/**/*.go". Segments are separated by
+// '/'. Directories, which can not contain matching files, are not walked.
+func (q *Query) Path(patterns ...string) *Query {
+	var alts [][]string
+	for _, pattern := range patterns {
+		expanded, err := expandBraces(pattern)
+		if err != nil {
+			return q.invalid(fmt.Errorf("find: path %q: %w", pattern, err))
+		}
+		for _, p := range expanded {
+			segs, err := splitPattern(p, "/", true)
+			if err != nil {
+				return q.invalid(fmt.Errorf("find: path %q: %w", pattern, err))
+			}
+			alts = append(alts, segs)
+		}
+	}
+	q.paths = append(q.paths, alts)
+	return q
+}
+
+// Prune does not walk the directories whose base name matches any of the
+// patterns of path.Match, like "node_modules" or ".*". The directories
+// themselves are still selected by the other predicates.
+func (q *Query) Prune(patterns ...string) *Query {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return q.invalid(fmt.Errorf("find: prune %q: %w", pattern, err))
+		}
+	}
+	q.prune = append(q.prune, patterns...)
+	return q
+}
+
+// Type selects the files of the given types, e.g. Regular|Symlink.
+func (q *Query) Type(types FileType) *Query {
+	return q.filter(func(_ string, info os.FileInfo) bool {
+		return typeOf(info)&types != 0
+	})
+}
+
+// Size selects the files whose size satisfies expr: a size with an optional
+// comparison <, <=, =, >= or > and an optional unit B, K, M, G or T
+// with binary multiples, like ">1MB", "<=4k" or "0".
+func (q *Query) Size(expr string) *Query {
+	cmp, err := parseSize(expr)
+	if err != nil {
+		return q.invalid(err)
+	}
+	return q.filter(func(_ string, info os.FileInfo) bool {
+		return cmp(info.Size())
+	})
+}
+
+// NewerThan selects the files modified after t.
+func (q *Query) NewerThan(t time.Time) *Query {
+	return q.filter(func(_ string, info os.FileInfo) bool {
+		return info.ModTime().After(t)
+	})
+}
+
+// OlderThan selects the files modified before t.
+func (q *Query) OlderThan(t time.Time) *Query {
+	return q.filter(func(_ string, info os.FileInfo) bool {
+		return info.ModTime().Before(t)
+	})
+}
+
+// MinDepth selects the files at least depth levels below the root,
+// the root has depth 0.
+func (q *Query) MinDepth(depth int) *Query {
+	q.minDepth = depth
+	return q
+}
+
+// MaxDepth selects the files at most depth levels below the root
+// and does not walk deeper.
+func (q *Query) MaxDepth(depth int) *Query {
+	q.maxDepth = depth
+	return q
+}
+
+// Filter selects the files for which fn returns true.
+func (q *Query) Filter(fn func(path string, info os.FileInfo) bool) *Query {
+	return q.filter(fn)
+}
+
+// WithOptions sets the options of the traversal, e.g. to follow symbolic links.
+func (q *Query) WithOptions(opts WalkOptions) *Query {
+	q.opts = opts
+	return q
+}
+
+// All returns an iterator over the paths and FileInfos of the selected
+// files in the order of WalkDir. It is compatible with iter.Seq2.
+// Errors reading the tree do not stop the iteration, they are
+// returned by Err once it is done.
+func (q *Query) All() func(yield func(string, os.FileInfo) bool) {
+	return func(yield func(string, os.FileInfo) bool) {
+		q.errs = nil
+		if q.err != nil {
+			q.errs = append(q.errs, q.err)
+			return
+		}
+		sep := string(q.fs.PathSeparator())
+		WalkDirWithOptions(q.fs, q.root, func(p string, d iofs.DirEntry, err error) error {
+			if err != nil {
+				q.errs = append(q.errs, err)
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				q.errs = append(q.errs, err)
+				return nil
+			}
+			var rel []string
+			if p != q.root {
+				rel = strings.Split(strings.TrimPrefix(p[len(q.root):], sep), sep)
+			}
+			if q.selects(p, rel, info) && !yield(p, info) {
+				return iofs.SkipAll
+			}
+			if d.IsDir() && q.prunes(rel, info) {
+				return iofs.SkipDir
+			}
+			return nil
+		}, q.opts)
+	}
+}
+
+// selects reports whether the file at path with the segments rel relative
+// to the root is selected.
+func (q *Query) selects(path string, rel []string, info os.FileInfo) bool {
+	if len(rel) < q.minDepth || q.maxDepth >= 0 && len(rel) > q.maxDepth {
+		return false
+	}
+	for _, alts := range q.paths {
+		matched := false
+		for _, segs := range alts {
+			if matchSegments(segs, rel) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, pred := range q.preds {
+		if !pred(path, info) {
+			return false
+		}
+	}
+	return true
+}
+
+// prunes reports whether the directory with the segments rel relative
+// to the root must not be walked.
+func (q *Query) prunes(rel []string, info os.FileInfo) bool {
+	if q.maxDepth >= 0 && len(rel) >= q.maxDepth {
+		return true
+	}
+	if len(rel) > 0 {
+		for _, pattern := range q.prune {
+			if ok, _ := path.Match(pattern, info.Name()); ok {
+				return true
+			}
+		}
+	}
+	for _, alts := range q.paths {
+		matched := false
+		for _, segs := range alts {
+			if matchPrefix(segs, rel) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns the errors of the last iteration joined by errors.Join,
+// including an invalid predicate.
+func (q *Query) Err() error {
+	return errors.Join(q.errs...)
+}
+
+// Paths returns the paths of all selected files.
+func (q *Query) Paths() ([]string, error) {
+	var paths []string
+	q.All()(func(path string, _ os.FileInfo) bool {
+		paths = append(paths, path)
+		return true
+	})
+	return paths, q.Err()
+}
+
+// sizeUnits are the binary multiples of the units of Query.Size.
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// parseSize parses the size expression of Query.Size.
+func parseSize(expr string) (func(int64) bool, error) {
+	s := strings.TrimSpace(expr)
+	op := ""
+	for _, o := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(s, o) {
+			op, s = o, strings.TrimSpace(s[len(o):])
+			break
+		}
+	}
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if err != nil || !ok {
+		return nil, fmt.Errorf("find: invalid size %q", expr)
+	}
+	size := int64(n * float64(unit))
+	switch op {
+	case "<":
+		return func(n int64) bool { return n < size }, nil
+	case "<=":
+		return func(n int64) bool { return n <= size }, nil
+	case ">":
+		return func(n int64) bool { return n > size }, nil
+	case ">=":
+		return func(n int64) bool { return n >= size }, nil
+	}
+	return func(n int64) bool { return n == size }, nil
+}
@@ -0,0 +1,119 @@
+package vfs_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
+)
+
+func makeFindTree(t *testing.T) vfs.Filesystem {
+	fs := memfs.Create()
+	makeGlobTree(t, fs, "")
+	if err := vfs.WriteFile(fs, "/a/big.bin", make([]byte, 3<<10), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Symlink("/a/x.go", "/c/link.go"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	return fs
+}
+
+func TestFind(t *testing.T) {
+	fs := makeFindTree(t)
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := vfs.Chtimes(fs, "/a/b/z.go", old, old); err != nil {
+		t.Fatalf("Chtimes: %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		query *vfs.Query
+		want  []string
+	}{
+		{"name", vfs.Find(fs, "/").Name("*.go"), []string{"/a/b/c/w.go", "/a/b/z.go", "/a/x.go", "/c/d.go", "/c/link.go"}},
+		{"names", vfs.Find(fs, "/a").Name("*.txt", "*.bin"), []string{"/a/big.bin", "/a/y.txt"}},
+		{"type", vfs.Find(fs, "/").Name("*.go").Type(vfs.Regular), []string{"/a/b/c/w.go", "/a/b/z.go", "/a/x.go", "/c/d.go"}},
+		{"symlink", vfs.Find(fs, "/").Type(vfs.Symlink), []string{"/c/link.go"}},
+		{"directory", vfs.Find(fs, "/a").Type(vfs.Directory), []string{"/a", "/a/b", "/a/b/c"}},
+		{"size", vfs.Find(fs, "/").Type(vfs.Regular).Size(">2k"), []string{"/a/big.bin"}},
+		{"size equal", vfs.Find(fs, "/a").Type(vfs.Regular).Size("3KiB"), []string{"/a/big.bin"}},
+		{"newer", vfs.Find(fs, "/a/b").Type(vfs.Regular).NewerThan(old.Add(time.Hour)), []string{"/a/b/c/w.go"}},
+		{"older", vfs.Find(fs, "/").OlderThan(old.Add(time.Hour)), []string{"/a/b/z.go"}},
+		{"max depth", vfs.Find(fs, "/").MaxDepth(1), []string{"/", "/a", "/c"}},
+		{"min depth", vfs.Find(fs, "/a").MinDepth(2).Type(vfs.Regular), []string{"/a/b/c/w.go", "/a/b/z.go"}},
+		{"path", vfs.Find(fs, "/").Path("a/**/*.go"), []string{"/a/b/c/w.go", "/a/b/z.go", "/a/x.go"}},
+		{"path braces", vfs.Find(fs, "/").Path("{a,c}/*.go"), []string{"/a/x.go", "/c/d.go", "/c/link.go"}},
+		{"prune", vfs.Find(fs, "/").Prune("b").Name("*.go"), []string{"/a/x.go", "/c/d.go", "/c/link.go"}},
+		{"filter", vfs.Find(fs, "/c").Filter(func(path string, _ os.FileInfo) bool { return strings.HasPrefix(path, "/c/.") }), []string{"/c/.hidden"}},
+		{"follow", vfs.Find(fs, "/c").Name("link.go").WithOptions(vfs.WalkOptions{FollowSymlinks: true}).Type(vfs.Regular), []string{"/c/link.go"}},
+	}
+	for _, test := range tests {
+		got, err := test.query.Paths()
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %s", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: found %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFindPruning(t *testing.T) {
+	fs := makeFindTree(t)
+	for _, test := range []struct {
+		query func(fs vfs.Filesystem) *vfs.Query
+		read  []string
+	}{
+		{func(fs vfs.Filesystem) *vfs.Query { return vfs.Find(fs, "/").MaxDepth(1) }, []string{"/"}},
+		{func(fs vfs.Filesystem) *vfs.Query { return vfs.Find(fs, "/").Path("a/*/*.go") }, []string{"/", "/a", "/a/b"}},
+		{func(fs vfs.Filesystem) *vfs.Query { return vfs.Find(fs, "/").Prune("a") }, []string{"/", "/c"}},
+	} {
+		counter := &readDirCounter{Filesystem: fs}
+		if _, err := test.query(counter).Paths(); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(counter.dirs, test.read) {
+			t.Errorf("Read %q, want %q", counter.dirs, test.read)
+		}
+	}
+}
+
+func TestFindIterator(t *testing.T) {
+	fs := makeFindTree(t)
+	var seq func(yield func(string, os.FileInfo) bool) = vfs.Find(fs, "/").Type(vfs.Regular).All()
+	var paths []string
+	seq(func(path string, info os.FileInfo) bool {
+		if info.IsDir() {
+			t.Errorf("Unexpected directory %s", path)
+		}
+		paths = append(paths, path)
+		return len(paths) < 2
+	})
+	if want := []string{"/a/b/c/w.go", "/a/b/z.go"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Iterated %q, want %q", paths, want)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	fs := makeFindTree(t)
+	for _, q := range []*vfs.Query{
+		vfs.Find(fs, "/").Size(">1XB"),
+		vfs.Find(fs, "/").Size("big"),
+		vfs.Find(fs, "/").Name("["),
+		vfs.Find(fs, "/").Path("{a"),
+	} {
+		if paths, err := q.Paths(); err == nil || paths != nil {
+			t.Errorf("Expected error, got %q", paths)
+		}
+	}
+
+	if _, err := vfs.Find(fs, "/missing").Paths(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package gitignore hides the files ignored by .gitignore and .ignore files
+// of a tree like git: by a filtering walk and by a filesystem wrapper.
+package gitignore
@@ -0,0 +1,32 @@
+package gitignore_test
+
+import (
+	"fmt"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/gitignore"
+	"github.com/lordofscripts/vfs/memfs"
+)
+
+func ExampleFS() {
+	mfs := memfs.Create()
+	vfs.MkdirAll(mfs, "/src/build", 0777)
+	vfs.WriteFile(mfs, "/src/.gitignore", []byte("build/\n*.log\n"), 0666)
+	vfs.WriteFile(mfs, "/src/main.go", nil, 0666)
+	vfs.WriteFile(mfs, "/src/debug.log", nil, 0666)
+	vfs.WriteFile(mfs, "/src/build/main", nil, 0666)
+
+	// Hide the ignored files below /src
+	fs := gitignore.Create(mfs, "/src")
+	fis, _ := fs.ReadDir("/src")
+	for _, fi := range fis {
+		fmt.Println(fi.Name())
+	}
+	if _, err := fs.Stat("/src/build/main"); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// .gitignore
+	// main.go
+	// stat /src/build/main: file does not exist
+}
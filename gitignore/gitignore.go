@@ -0,0 +1,92 @@
+package gitignore
+
+import (
+	"context"
+	"os"
+	filepath "path"
+	"time"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// FS hides the files of a tree ignored by its ignore files, see Matcher.
+// Stat and Lstat of ignored files and ReadDir of ignored directories fail
+// with an *os.PathError wrapping os.ErrNotExist, ReadDir omits the ignored
+// entries. The other operations are forwarded to the wrapped filesystem.
+type FS struct {
+	vfs.Filesystem
+	m *Matcher
+}
+
+// Create returns a filesystem hiding the files of the tree rooted at root,
+// which are ignored by the DefaultFiles of its directories.
+func Create(fs vfs.Filesystem, root string) *FS {
+	return &FS{Filesystem: fs, m: NewMatcher(fs, root)}
+}
+
+// Matcher returns the Matcher deciding which files are hidden.
+func (fs *FS) Matcher() *Matcher {
+	return fs.m
+}
+
+// WithContext implements vfs.FilesystemContext
+// and binds ctx to the wrapped filesystem.
+func (fs *FS) WithContext(ctx context.Context) vfs.Filesystem {
+	return &FS{Filesystem: vfs.WithContext(ctx, fs.Filesystem), m: fs.m}
+}
+
+// hidden returns the error of an operation on an ignored file.
+func hidden(op, path string) error {
+	return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+}
+
+// Stat implements vfs.Filesystem.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(name)
+	if err == nil && fs.m.Ignored(name, fi.IsDir()) {
+		return nil, hidden("stat", name)
+	}
+	return fi, err
+}
+
+// Lstat implements vfs.Filesystem.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(name)
+	if err == nil && fs.m.Ignored(name, fi.IsDir()) {
+		return nil, hidden("lstat", name)
+	}
+	return fi, err
+}
+
+// ReadDir implements vfs.Filesystem.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	if fs.m.Ignored(path, true) {
+		return nil, hidden("readdir", path)
+	}
+	fis, err := fs.Filesystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	visible := fis[:0]
+	for _, fi := range fis {
+		if !fs.m.Ignored(filepath.Join(path, fi.Name()), fi.IsDir()) {
+			visible = append(visible, fi)
+		}
+	}
+	return visible, nil
+}
+
+// Readlink implements vfs.ReadlinkFS.
+func (fs *FS) Readlink(name string) (string, error) {
+	return vfs.Readlink(fs.Filesystem, name)
+}
+
+// Chmod implements vfs.ChmodFS.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	return vfs.Chmod(fs.Filesystem, name, mode)
+}
+
+// Chtimes implements vfs.ChtimesFS.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return vfs.Chtimes(fs.Filesystem, name, atime, mtime)
+}
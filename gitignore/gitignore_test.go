@@ -0,0 +1,148 @@
+package gitignore
+
+import (
+	iofs "io/fs"
+	"os"
+	filepath "path"
+	"reflect"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
+)
+
+var ignoreFiles = map[string]string{
+	"/repo/.gitignore":     "# comment\n*.log\n!keep.log\n/build/\n!build/keep\nnode_modules/\ndocs/**/*.tmp\nfoo/**\n\\#hash\ntrailing   \n",
+	"/repo/sub/.gitignore": "!*.log\nlocal\n!x.txt\n",
+	"/repo/sub/.ignore":    "x.txt\n",
+}
+
+var treeFiles = []string{
+	"a.log", "keep.log", "build/out", "build/keep", "src/build/x", "node_modules/pkg/index.js",
+	"src/node_modules", "docs/x.tmp", "docs/a/b/x.tmp", "foo/bar", "#hash", "trailing",
+	"sub/a.log", "sub/local", "sub/x.txt", ".git/config",
+}
+
+func repofs(t *testing.T) vfs.Filesystem {
+	fs := memfs.Create()
+	for name, data := range ignoreFiles {
+		vfs.MkdirAll(fs, filepath.Dir(name), 0777)
+		if err := vfs.WriteFile(fs, name, []byte(data), 0666); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	for _, name := range treeFiles {
+		name = filepath.Join("/repo", name)
+		vfs.MkdirAll(fs, filepath.Dir(name), 0777)
+		if err := vfs.WriteFile(fs, name, nil, 0666); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	return fs
+}
+
+var visible = []string{
+	"/repo", "/repo/.gitignore", "/repo/docs", "/repo/docs/a", "/repo/docs/a/b", "/repo/foo", "/repo/keep.log",
+	"/repo/src", "/repo/src/build", "/repo/src/build/x", "/repo/src/node_modules",
+	"/repo/sub", "/repo/sub/.gitignore", "/repo/sub/.ignore", "/repo/sub/a.log",
+}
+
+func walked(t *testing.T, walk func(fn iofs.WalkDirFunc) error) []string {
+	var paths []string
+	err := walk(func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	fs := repofs(t)
+	got := walked(t, func(fn iofs.WalkDirFunc) error { return Walk(fs, "/repo", fn) })
+	if !reflect.DeepEqual(got, visible) {
+		t.Errorf("Walked %q, want %q", got, visible)
+	}
+}
+
+func TestFS(t *testing.T) {
+	fs := Create(repofs(t), "/repo")
+	got := walked(t, func(fn iofs.WalkDirFunc) error { return vfs.WalkDir(fs, "/repo", fn) })
+	if !reflect.DeepEqual(got, visible) {
+		t.Errorf("Walked %q, want %q", got, visible)
+	}
+
+	for _, name := range []string{"/repo/a.log", "/repo/build", "/repo/build/keep", "/repo/foo/bar", "/repo/.git"} {
+		if _, err := fs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Stat(%s): expected not exist error, got %v", name, err)
+		}
+		if _, err := fs.Lstat(name); !os.IsNotExist(err) {
+			t.Errorf("Lstat(%s): expected not exist error, got %v", name, err)
+		}
+	}
+	if _, err := fs.ReadDir("/repo/node_modules/pkg"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if _, err := fs.Stat("/repo/sub/a.log"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// Files outside of the tree are never hidden.
+	vfs.WriteFile(fs, "/other.log", nil, 0666)
+	if _, err := fs.Stat("/other.log"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		line, base string
+		path       string
+		isDir      bool
+		match      bool
+	}{
+		{"*.o", "", "a/b/c.o", false, true},
+		{"*.o", "sub", "c.o", false, false},
+		{"*.o", "sub", "sub/x/c.o", false, true},
+		{"/a.o", "", "x/a.o", false, false},
+		{"/a.o", "", "a.o", false, true},
+		{"a/b", "", "x/a/b", false, false},
+		{"a/b", "", "a/b", false, true},
+		{"bin/", "", "x/bin", false, false},
+		{"bin/", "", "x/bin", true, true},
+		{"**/bin", "", "x/y/bin", false, true},
+		{"a/**/b", "", "a/b", false, true},
+		{"a/**/b", "", "a/x/y/b", false, true},
+		{"a/**", "", "a", true, false},
+		{"a/**", "", "a/x/y", false, true},
+		{"{a,b}", "", "a", false, false},
+		{"{a,b}", "", "{a,b}", false, true},
+		{"[!a]*", "", "bc", false, true},
+		{"\\!x", "", "!x", false, true},
+		{"x\\ ", "", "x ", false, true},
+	}
+	for _, test := range tests {
+		p, ok := ParsePattern(test.line, test.base)
+		if !ok {
+			t.Errorf("ParsePattern(%q) skipped", test.line)
+			continue
+		}
+		if match := p.Match(test.path, test.isDir); match != test.match {
+			t.Errorf("%q in %q: Match(%q, %v) = %v, want %v", test.line, test.base, test.path, test.isDir, match, test.match)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "/", "!"} {
+		if _, ok := ParsePattern(line, ""); ok {
+			t.Errorf("ParsePattern(%q) not skipped", line)
+		}
+	}
+	if p, _ := ParsePattern("!keep/", ""); !p.Negate || !p.DirOnly {
+		t.Errorf("Unexpected pattern %+v", p)
+	}
+}
@@ -0,0 +1,136 @@
+package gitignore
+
+import (
+	iofs "io/fs"
+	filepath "path"
+	"strings"
+	"sync"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// DefaultFiles are the names of the ignore files read by a Matcher,
+// later files take precedence.
+var DefaultFiles = []string{".gitignore", ".ignore"}
+
+// Matcher decides which files of a tree are ignored by the ignore files
+// found in its directories. Like git, the patterns of deeper ignore files
+// take precedence over those of their parents, the last matching pattern
+// decides, and the files of an ignored directory can not be re-included.
+// The ".git" directories are always ignored.
+//
+// The ignore files of a directory are read once, when the first path
+// below it is matched. A Matcher is safe for concurrent use.
+type Matcher struct {
+	fs    vfs.Filesystem
+	root  string
+	files []string
+
+	mu   sync.Mutex
+	dirs map[string][]Pattern // patterns by directory relative to root
+}
+
+// NewMatcher returns a Matcher for the tree rooted at root, which reads the
+// ignore files of the given names, DefaultFiles if there are none.
+func NewMatcher(fs vfs.Filesystem, root string, files ...string) *Matcher {
+	if len(files) == 0 {
+		files = DefaultFiles
+	}
+	return &Matcher{fs: fs, root: filepath.Clean(root), files: files, dirs: map[string][]Pattern{}}
+}
+
+// Root returns the root of the tree.
+func (m *Matcher) Root() string {
+	return m.root
+}
+
+// rel returns the segments of path relative to the root and reports false
+// if path is not below the root.
+func (m *Matcher) rel(path string) ([]string, bool) {
+	path = filepath.Clean(path)
+	switch {
+	case path == m.root:
+		return nil, true
+	case m.root == "/" && strings.HasPrefix(path, "/"):
+		return strings.Split(path[1:], "/"), true
+	case m.root == ".":
+		return strings.Split(path, "/"), !strings.HasPrefix(path, "/") && path != ".." && !strings.HasPrefix(path, "../")
+	case strings.HasPrefix(path, m.root+"/"):
+		return strings.Split(path[len(m.root)+1:], "/"), true
+	}
+	return nil, false
+}
+
+// patterns returns the patterns of the ignore files in the directory dir
+// relative to the root.
+func (m *Matcher) patterns(dir string) []Pattern {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns
+	}
+	var patterns []Pattern
+	for _, name := range m.files {
+		data, err := vfs.ReadFile(m.fs, filepath.Join(m.root, dir, name))
+		if err == nil {
+			patterns = append(patterns, Parse(data, dir)...)
+		}
+	}
+	m.dirs[dir] = patterns
+	return patterns
+}
+
+// Ignored reports whether the file at path is ignored. Paths outside of
+// the tree are never ignored.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	rel, ok := m.rel(path)
+	if !ok || len(rel) == 0 {
+		return false
+	}
+	for i := 1; i < len(rel); i++ {
+		if m.match(rel[:i], true) {
+			return true
+		}
+	}
+	return m.match(rel, isDir)
+}
+
+// match reports whether the patterns of the directories of the tree
+// ignore the path, assuming its directories are not ignored.
+func (m *Matcher) match(rel []string, isDir bool) bool {
+	if isDir && rel[len(rel)-1] == ".git" {
+		return true
+	}
+	path := strings.Join(rel, "/")
+	ignored := false
+	for i := range rel {
+		for _, p := range m.patterns(strings.Join(rel[:i], "/")) {
+			if p.Match(path, isDir) {
+				ignored = !p.Negate
+			}
+		}
+	}
+	return ignored
+}
+
+// Walk walks the tree like vfs.WalkDir but skips the ignored files
+// and directories.
+func (m *Matcher) Walk(fn iofs.WalkDirFunc) error {
+	return vfs.WalkDir(m.fs, m.root, func(path string, d iofs.DirEntry, err error) error {
+		if d != nil && path != m.root {
+			if rel, ok := m.rel(path); ok && m.match(rel, d.IsDir()) {
+				if d.IsDir() {
+					return iofs.SkipDir
+				}
+				return nil
+			}
+		}
+		return fn(path, d, err)
+	})
+}
+
+// Walk walks the tree rooted at root like vfs.WalkDir but skips the files
+// and directories ignored by its ignore files.
+func Walk(fs vfs.Filesystem, root string, fn iofs.WalkDirFunc) error {
+	return NewMatcher(fs, root).Walk(fn)
+}
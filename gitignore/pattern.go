@@ -0,0 +1,112 @@
+package gitignore
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// Pattern is a line of an ignore file.
+type Pattern struct {
+	// Base is the directory of the ignore file relative to the root
+	// of the tree, "" for the root.
+	Base string
+
+	// Negate re-includes the files matched by the pattern, "!pattern".
+	Negate bool
+
+	// DirOnly matches only directories, "pattern/".
+	DirOnly bool
+
+	// Glob is the pattern of vfs.DoublestarMatch matching the paths
+	// relative to Base.
+	Glob string
+}
+
+// Parse returns the patterns of an ignore file in the directory base
+// relative to the root of the tree. Blank lines and comments are skipped.
+func Parse(data []byte, base string) []Pattern {
+	var patterns []Pattern
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if p, ok := ParsePattern(scanner.Text(), base); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// ParsePattern parses a line of an ignore file in the directory base.
+// It reports false for blank lines and comments.
+//
+// Like git, a pattern containing a slash other than a trailing one is
+// anchored to base, otherwise it matches at any level below base.
+// "**" matches any number of directories, braces are literal.
+func ParsePattern(line, base string) (Pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+
+	p := Pattern{Base: base}
+	if strings.HasPrefix(line, "!") {
+		p.Negate, line = true, line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.DirOnly, line = true, strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+	anchored := strings.Contains(line, "/")
+	line = escapeBraces(strings.TrimPrefix(line, "/"))
+	if !anchored {
+		line = "**/" + line
+	}
+	if strings.HasSuffix(line, "/**") {
+		// Matches the contents of a directory only.
+		line += "/*"
+	}
+	p.Glob = line
+	return p, true
+}
+
+// escapeBraces escapes the braces, which are literal in ignore files.
+func escapeBraces(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(pattern[i])
+		case c == '{' || c == '}':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Match reports whether the pattern matches the path relative to the root
+// of the tree, regardless of Negate.
+func (p Pattern) Match(path string, isDir bool) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+	if p.Base != "" {
+		if !strings.HasPrefix(path, p.Base+"/") {
+			return false
+		}
+		path = path[len(p.Base)+1:]
+	}
+	ok, _ := vfs.DoublestarMatch(p.Glob, path)
+	return ok
+}
@@ -0,0 +1,301 @@
+package vfs
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// GlobOptions configure GlobWithOptions.
+type GlobOptions struct {
+	// Doublestar enables the extended syntax of DoublestarMatch:
+	// "**" segments, brace expansion and negated classes like [!a-z].
+	Doublestar bool
+}
+
+// Glob returns the names of all files matching pattern or nil if there is
+// no matching file. The syntax of patterns is the same as in path.Match,
+// segments are separated by the PathSeparator of fs. The pattern may describe
+// hierarchical names such as /usr/*/bin/ed.
+//
+// Only the directories the pattern leads to are read, segments without
+// meta characters are not read at all. Glob ignores I/O errors such as
+// unreadable directories. The only possible returned error is
+// path.ErrBadPattern, when pattern is malformed.
+// The names are returned in lexical order.
+func Glob(fs Filesystem, pattern string) ([]string, error) {
+	return GlobWithOptions(fs, pattern, GlobOptions{})
+}
+
+// GlobWithOptions returns the names of all files matching pattern like Glob
+// according to the given options.
+//
+// With opts.Doublestar a "**" segment matches any number of directories,
+// including none. It does not follow symbolic links to directories.
+func GlobWithOptions(fs Filesystem, pattern string, opts GlobOptions) ([]string, error) {
+	sep := string(fs.PathSeparator())
+	patterns := []string{pattern}
+	if opts.Doublestar {
+		var err error
+		if patterns, err = expandBraces(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	g := &globber{fs: fs, sep: sep, doublestar: opts.Doublestar, matches: map[string]bool{}}
+	for _, p := range patterns {
+		segs, err := splitPattern(p, sep, opts.Doublestar)
+		if err != nil {
+			return nil, err
+		}
+		dir := ""
+		if strings.HasPrefix(p, sep) {
+			dir = sep
+		}
+		g.glob(dir, segs)
+	}
+	if len(g.matches) == 0 {
+		return nil, nil
+	}
+	matches := make([]string, 0, len(g.matches))
+	for name := range g.matches {
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// DoublestarMatch reports whether name matches the shell pattern with
+// the extensions of GlobOptions.Doublestar. Segments are separated by '/'.
+//
+//	'**'          a whole segment, matches any number of segments
+//	'{' { term-list } '}'
+//	              matches any of the comma separated term-lists,
+//	              which may contain nested braces
+//	'[' '!' { character-range } ']'
+//	              negated character class like [^...]
+//
+// The other syntax is the same as in path.Match. The only possible returned
+// error is path.ErrBadPattern, when pattern is malformed.
+func DoublestarMatch(pattern, name string) (bool, error) {
+	patterns, err := expandBraces(pattern)
+	if err != nil {
+		return false, err
+	}
+	names := strings.FieldsFunc(name, func(r rune) bool { return r == '/' })
+	matched := false
+	for _, p := range patterns {
+		segs, err := splitPattern(p, "/", true)
+		if err != nil {
+			return false, err
+		}
+		if matched || strings.HasPrefix(p, "/") != strings.HasPrefix(name, "/") && (len(segs) == 0 || segs[0] != "**") {
+			continue
+		}
+		matched = matchSegments(segs, names)
+	}
+	return matched, nil
+}
+
+// globber collects the files matching the patterns of a glob.
+type globber struct {
+	fs         Filesystem
+	sep        string
+	doublestar bool
+	matches    map[string]bool
+}
+
+// join returns the path of name in dir, where "" is the current directory.
+func (g *globber) join(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return joinPath(g.fs, dir, name)
+}
+
+// glob adds the files below dir matching the pattern segments.
+func (g *globber) glob(dir string, segs []string) {
+	for len(segs) > 0 && !hasMeta(segs[0]) {
+		dir, segs = g.join(dir, segs[0]), segs[1:]
+	}
+	if len(segs) == 0 {
+		if dir != "" {
+			if _, err := g.fs.Lstat(dir); err == nil {
+				g.matches[dir] = true
+			}
+		}
+		return
+	}
+
+	name := dir
+	if name == "" {
+		name = "."
+	}
+	infos, err := g.fs.ReadDir(name)
+	if err != nil {
+		return
+	}
+	seg, rest := segs[0], segs[1:]
+	if g.doublestar && seg == "**" {
+		g.glob(dir, rest)
+		for _, info := range infos {
+			name := g.join(dir, info.Name())
+			if len(rest) == 0 {
+				g.matches[name] = true
+			}
+			if info.IsDir() {
+				g.glob(name, segs)
+			}
+		}
+		return
+	}
+	for _, info := range infos {
+		if ok, _ := path.Match(seg, info.Name()); !ok {
+			continue
+		}
+		if name := g.join(dir, info.Name()); len(rest) == 0 {
+			g.matches[name] = true
+		} else {
+			g.glob(name, rest)
+		}
+	}
+}
+
+// hasMeta reports whether a pattern segment needs to be matched.
+func hasMeta(seg string) bool {
+	return strings.ContainsAny(seg, `*?[\`)
+}
+
+// splitPattern splits a pattern without braces in validated segments.
+// Empty segments are dropped and consecutive "**" segments are collapsed.
+func splitPattern(pattern, sep string, doublestar bool) ([]string, error) {
+	if doublestar {
+		pattern = negateClasses(pattern)
+	}
+	var segs []string
+	for _, seg := range strings.Split(pattern, sep) {
+		if seg == "" || doublestar && seg == "**" && len(segs) > 0 && segs[len(segs)-1] == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// matchSegments reports whether the name segments match the pattern segments.
+func matchSegments(segs, names []string) bool {
+	for len(segs) > 0 {
+		if segs[0] == "**" {
+			if len(segs) == 1 {
+				return true
+			}
+			for i := range names {
+				if matchSegments(segs[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(segs[0], names[0]); !ok {
+			return false
+		}
+		segs, names = segs[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// negateClasses replaces the negation [! of character classes by [^.
+func negateClasses(pattern string) string {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			c = pattern[i]
+		case !inClass && c == '[':
+			inClass = true
+			if i+1 < len(pattern) && pattern[i+1] == '!' {
+				b.WriteString("[^")
+				i++
+				continue
+			}
+		case inClass && c == ']':
+			inClass = false
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// expandBraces returns the patterns described by the brace expressions
+// of pattern. Braces in character classes and escaped braces are literal.
+func expandBraces(pattern string) ([]string, error) {
+	start, depth := -1, 0
+	var commas []int
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case c == ',' && depth == 1:
+			commas = append(commas, i)
+		case c == '}' && depth > 0:
+			if depth--; depth > 0 {
+				continue
+			}
+			var patterns []string
+			prefix, suffix := pattern[:start], pattern[i+1:]
+			from := start + 1
+			for _, to := range append(commas, i) {
+				expanded, err := expandBraces(prefix + pattern[from:to] + suffix)
+				if err != nil {
+					return nil, err
+				}
+				patterns = append(patterns, expanded...)
+				from = to + 1
+			}
+			return patterns, nil
+		}
+	}
+	if depth > 0 {
+		return nil, path.ErrBadPattern
+	}
+	return []string{pattern}, nil
+}
+
+// matchPrefix reports whether the name segments may be the prefix of
+// a name matching the pattern segments, i.e. whether a directory
+// may contain matching files.
+func matchPrefix(segs, names []string) bool {
+	for len(names) > 0 {
+		if len(segs) == 0 {
+			return false
+		}
+		if segs[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(segs[0], names[0]); !ok {
+			return false
+		}
+		segs, names = segs[1:], names[1:]
+	}
+	return true
+}
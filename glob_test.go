@@ -0,0 +1,183 @@
+package vfs_test
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
+)
+
+var globFiles = []string{"a/x.go", "a/y.txt", "a/b/z.go", "a/b/c/w.go", "c/.hidden", "c/d.go"}
+
+func makeGlobTree(t *testing.T, fs vfs.Filesystem, root string) {
+	for _, name := range globFiles {
+		name = root + "/" + name
+		if err := vfs.MkdirAll(fs, path.Dir(name), 0777); err != nil {
+			t.Fatalf("MkdirAll: %s", err)
+		}
+		if err := vfs.WriteFile(fs, name, nil, 0666); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+}
+
+// readDirCounter records the directories read.
+type readDirCounter struct {
+	vfs.Filesystem
+	dirs []string
+}
+
+func (fs *readDirCounter) ReadDir(path string) ([]os.FileInfo, error) {
+	fs.dirs = append(fs.dirs, path)
+	return fs.Filesystem.ReadDir(path)
+}
+
+func TestGlob(t *testing.T) {
+	fs := memfs.Create()
+	makeGlobTree(t, fs, "")
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"/a/*.go", []string{"/a/x.go"}},
+		{"/*/*.go", []string{"/a/x.go", "/c/d.go"}},
+		{"/*/?.*", []string{"/a/x.go", "/a/y.txt", "/c/d.go"}},
+		{"/c/*", []string{"/c/.hidden", "/c/d.go"}},
+		{"/a/[bx]*", []string{"/a/b", "/a/x.go"}},
+		{"/a/b", []string{"/a/b"}},
+		{"/a/**/*.go", []string{"/a/b/z.go"}},
+		{"/a/{x,y}.go", nil},
+		{"/missing/*", nil},
+	}
+	for _, test := range tests {
+		got, err := vfs.Glob(fs, test.pattern)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Glob(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+
+	if _, err := vfs.Glob(fs, "/a/["); err != path.ErrBadPattern {
+		t.Errorf("Expected bad pattern, got %v", err)
+	}
+
+	counter := &readDirCounter{Filesystem: fs}
+	if got, _ := vfs.Glob(counter, "/a/b/*.go"); !reflect.DeepEqual(got, []string{"/a/b/z.go"}) {
+		t.Errorf("Unexpected matches: %q", got)
+	}
+	if !reflect.DeepEqual(counter.dirs, []string{"/a/b"}) {
+		t.Errorf("Read %q, want only /a/b", counter.dirs)
+	}
+}
+
+func TestGlobOS(t *testing.T) {
+	td := t.TempDir()
+	makeGlobTree(t, vfs.OS(), filepath.ToSlash(td))
+
+	for _, pattern := range []string{"*/*.go", "a/*", "*/[a-c]/*", "a/b/c/w.go", "*/missing"} {
+		pattern = filepath.Join(td, pattern)
+		want, _ := filepath.Glob(pattern)
+		got, err := vfs.Glob(vfs.OS(), pattern)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Glob(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestGlobDoublestar(t *testing.T) {
+	fs := memfs.Create()
+	makeGlobTree(t, fs, "")
+	opts := vfs.GlobOptions{Doublestar: true}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"/**/*.go", []string{"/a/b/c/w.go", "/a/b/z.go", "/a/x.go", "/c/d.go"}},
+		{"/a/**/*.go", []string{"/a/b/c/w.go", "/a/b/z.go", "/a/x.go"}},
+		{"/a/**/**/c", []string{"/a/b/c"}},
+		{"/a/b/**", []string{"/a/b", "/a/b/c", "/a/b/c/w.go", "/a/b/z.go"}},
+		{"/a/{x,b/z}.go", []string{"/a/b/z.go", "/a/x.go"}},
+		{"/{a,c}/{*.txt,.h*}", []string{"/a/y.txt", "/c/.hidden"}},
+		{"/a/{b/{c,d},e}", []string{"/a/b/c"}},
+		{"/a/[!b]*", []string{"/a/x.go", "/a/y.txt"}},
+		{"/a/[{]*", nil},
+	}
+	for _, test := range tests {
+		got, err := vfs.GlobWithOptions(fs, test.pattern, opts)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GlobWithOptions(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+
+	for _, pattern := range []string{"/{a", "/a/{[,b}"} {
+		if _, err := vfs.GlobWithOptions(fs, pattern, opts); err != path.ErrBadPattern {
+			t.Errorf("Expected bad pattern for %q, got %v", pattern, err)
+		}
+	}
+
+	counter := &readDirCounter{Filesystem: fs}
+	vfs.GlobWithOptions(counter, "/a/b/**/*.go", opts)
+	for _, dir := range counter.dirs {
+		if dir != "/a/b" && dir != "/a/b/c" {
+			t.Errorf("Unexpected read of %s", dir)
+		}
+	}
+}
+
+func TestDoublestarMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"**", "a/b/c", true},
+		{"**/c", "c", true},
+		{"**/c", "/a/b/c", true},
+		{"a/**/c", "a/c", true},
+		{"a/**/c", "a/b/b/c", true},
+		{"a/**/c", "a/b/d", false},
+		{"a/**", "a", true},
+		{"a/*", "a/b/c", false},
+		{"a**/c", "ab/c", true},
+		{"a**/c", "a/b/c", false},
+		{"/a/*.go", "/a/x.go", true},
+		{"/a/*.go", "a/x.go", false},
+		{"a/*.go", "/a/x.go", false},
+		{"*.{go,txt}", "x.txt", true},
+		{"*.{go,txt}", "x.md", false},
+		{"{a,b/{c,d}}/x", "b/d/x", true},
+		{"[!a]*", "abc", false},
+		{"[!a]*", "bcd", true},
+		{"[^a]*", "bcd", true},
+		{`\{a,b}`, "{a,b}", true},
+		{"x{", "x{", false},
+	}
+	for _, test := range tests {
+		match, err := vfs.DoublestarMatch(test.pattern, test.name)
+		if test.pattern == "x{" {
+			if err != path.ErrBadPattern {
+				t.Errorf("Expected bad pattern for %q, got %v", test.pattern, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", test.pattern, err)
+		}
+		if match != test.match {
+			t.Errorf("DoublestarMatch(%q, %q) = %v, want %v", test.pattern, test.name, match, test.match)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package memfs
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lordofscripts/vfs"
+)
+
+func init() {
+	vfs.RegisterBackend("mem", newBackend)
+}
+
+// newBackend creates a MemFS for the "mem" backend.
+// The source is empty for an empty filesystem, otherwise it is the path of
+// a txtar archive on the filesystem of the OS populating it, see FromTxtar.
+// The sizes of the options must be positive:
+//
+//	chunk[=<size>]   use chunked buffers, see WithChunkedBuffers
+//	maxbytes=<n>     limit the allocated bytes, see WithLimits
+//	maxnodes=<n>     limit the number of nodes, see WithLimits
+func newBackend(source string, options map[string]string) (vfs.Filesystem, string, error) {
+	var maxBytes, maxNodes int64
+	chunked, chunkSize := false, 0
+	for key, value := range options {
+		var err error
+		switch key {
+		case "chunk":
+			chunked = true
+			if value != "" {
+				chunkSize, err = strconv.Atoi(value)
+			}
+		case "maxbytes":
+			maxBytes, err = strconv.ParseInt(value, 10, 64)
+		case "maxnodes":
+			maxNodes, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return nil, "", fmt.Errorf("mem: unknown option %q", key)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("mem: invalid option %s=%q", key, value)
+		}
+		if (key == "chunk" && value != "" && chunkSize <= 0) ||
+			(key == "maxbytes" && maxBytes <= 0) || (key == "maxnodes" && maxNodes <= 0) {
+			return nil, "", fmt.Errorf("mem: option %s=%q must be positive", key, value)
+		}
+	}
+
+	// The fixture is created with the options applied
+	fs := Create().WithLimits(maxBytes, maxNodes)
+	if chunked {
+		fs.WithChunkedBuffers(chunkSize)
+	}
+	if source != "" {
+		data, err := vfs.ReadFile(vfs.OS(), source)
+		if err != nil {
+			return nil, "", err
+		}
+		entries, err := txtarEntries(data)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := fromFixture(fs, entries); err != nil {
+			return nil, "", err
+		}
+	}
+	return fs, "/", nil
+}
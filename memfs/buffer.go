@@ -17,6 +17,16 @@ type Buffer interface {
 	Truncate(int64) error
 }
 
+// contentBuffer is the Buffer holding the content of a MemFile,
+// which is implemented by Buf and ChunkedBuf.
+type contentBuffer interface {
+	Buffer
+	io.WriterAt
+
+	// Len returns the size of the Buffer.
+	Len() int64
+}
+
 // MinBufferSize is the minimal initial allocated buffer size
 const MinBufferSize = 512
 
@@ -27,6 +37,10 @@ var ErrTooLarge = errors.New("volume too large")
 type Buf struct {
 	buf *[]byte
 	ptr int64
+
+	// reserve is called before the capacity changes by delta bytes,
+	// if it returns an error the buffer does not grow.
+	reserve func(delta int64) error
 }
 
 // NewBuffer creates a new data volume based on a buffer
@@ -70,17 +84,33 @@ func (v *Buf) Seek(offset int64, whence int) (int64, error) {
 // It returns the number of bytes written and an error if any.
 // Write returns non-nil error when n!=len(p).
 func (v *Buf) Write(p []byte) (int, error) {
-	l := len(p)
-	writeEnd := int(v.ptr) + l - len(*v.buf)
+	n, err := v.WriteAt(p, v.ptr)
+	v.ptr += int64(n)
+	return n, err
+}
+
+// WriteAt writes len(p) bytes to the Buffer starting at byte offset off.
+// If off is beyond the end of the Buffer, the gap is filled with zeros.
+// It returns the number of bytes written and an error if any.
+// WriteAt returns non-nil error when n!=len(p).
+func (v *Buf) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("WriteAt: negative offset")
+	}
+	writeEnd := off + int64(len(p)) - int64(len(*v.buf))
 	if writeEnd > 0 {
-		err := v.grow(writeEnd)
+		err := v.grow(int(writeEnd))
 		if err != nil {
 			return 0, err
 		}
 	}
-	copy((*v.buf)[v.ptr:], p)
-	v.ptr += int64(l)
-	return l, nil
+	copy((*v.buf)[off:], p)
+	return len(p), nil
+}
+
+// Len returns the size of the Buffer.
+func (v *Buf) Len() int64 {
+	return int64(len(*v.buf))
 }
 
 // Close the buffer. Currently no effect.
@@ -152,17 +182,38 @@ func (v *Buf) grow(n int) error {
 		if size < m+n {
 			size = m + n + MinBufferSize
 		}
+		if err := v.reserveCap(size); err != nil {
+			// Without room for the slack grow to the exact size
+			size = m + n
+			if err := v.reserveCap(size); err != nil {
+				return err
+			}
+		}
 		buf, err := makeSlice(size)
 		if err != nil {
+			if v.reserve != nil {
+				v.reserve(int64(cap(*v.buf) - size))
+			}
 			return err
 		}
 		copy(buf, *v.buf)
 		*v.buf = buf
+	} else {
+		// The capacity may still hold data of a previous Truncate
+		clear((*v.buf)[m : m+n])
 	}
 	*v.buf = (*v.buf)[0 : m+n]
 	return nil
 }
 
+// reserveCap reserves the memory to change the capacity to size bytes.
+func (v *Buf) reserveCap(size int) error {
+	if v.reserve == nil {
+		return nil
+	}
+	return v.reserve(int64(size - cap(*v.buf)))
+}
+
 // makeSlice allocates a slice of size n. If the allocation fails, it panics
 // with ErrTooLarge.
 func makeSlice(n int) (b []byte, err error) {
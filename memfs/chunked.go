@@ -0,0 +1,259 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+)
+
+// DefaultChunkSize is the chunk size used by chunked buffers if none is given.
+const DefaultChunkSize = 64 * 1024
+
+// ChunkedBuf is a Buffer storing its data in fixed-size chunks.
+// Chunks are only allocated once data is written to them, regions which
+// were never written (holes) are not allocated and read as zero bytes.
+// In contrast to Buf, growing a ChunkedBuf never copies existing data and
+// Truncate to a larger size allocates nothing, which makes large and
+// sparse files cheap.
+//
+// Like on a real filesystem, it is possible to Seek beyond the end of the
+// Buffer, a following Write extends the Buffer and leaves a hole.
+//
+// A ChunkedBuf is not safe for concurrent use. Buffers sharing their chunks,
+// like the handles of a file of a MemFS, must be guarded by a common lock,
+// which is also held for Len and Allocated.
+type ChunkedBuf struct {
+	data *chunkStore
+	ptr  int64
+}
+
+// chunkStore holds the chunks of a ChunkedBuf.
+// It may be shared by multiple ChunkedBufs each having its own offset.
+type chunkStore struct {
+	chunkSize int64
+	size      int64
+	chunks    map[int64][]byte
+
+	// reserve is called before chunks are allocated or released,
+	// if it returns an error no chunk is allocated.
+	reserve func(delta int64) error
+}
+
+// NewChunkedBuffer creates a new empty ChunkedBuf with the given chunk size.
+// If chunkSize is not positive, DefaultChunkSize is used.
+func NewChunkedBuffer(chunkSize int) *ChunkedBuf {
+	return newChunkedBuffer(newChunkStore(chunkSize))
+}
+
+func newChunkedBuffer(data *chunkStore) *ChunkedBuf {
+	return &ChunkedBuf{data: data}
+}
+
+func newChunkStore(chunkSize int) *chunkStore {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &chunkStore{
+		chunkSize: int64(chunkSize),
+		chunks:    make(map[int64][]byte),
+	}
+}
+
+// Len returns the size of the Buffer including holes.
+// Like all methods, it must not run concurrently with writes.
+func (v *ChunkedBuf) Len() int64 {
+	return v.data.size
+}
+
+// Allocated returns the number of bytes allocated by chunks.
+// Like all methods, it must not run concurrently with writes.
+func (v *ChunkedBuf) Allocated() int64 {
+	return int64(len(v.data.chunks)) * v.data.chunkSize
+}
+
+// Seek sets the offset for the next Read or Write on the buffer to offset,
+// interpreted according to whence:
+//
+//	0 (os.SEEK_SET) means relative to the origin of the file
+//	1 (os.SEEK_CUR) means relative to the current offset
+//	2 (os.SEEK_END) means relative to the end of the file
+//
+// Seeking beyond the end of the Buffer is allowed.
+// It returns the new offset and an error, if any.
+func (v *ChunkedBuf) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = v.ptr + offset
+	case io.SeekEnd:
+		abs = v.data.size + offset
+	default:
+		return 0, errors.New("Seek: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("Seek: negative position")
+	}
+	v.ptr = abs
+	return abs, nil
+}
+
+// Write writes len(p) byte to the Buffer starting at the current offset.
+// It returns the number of bytes written and an error if any.
+// Write returns non-nil error when n!=len(p).
+func (v *ChunkedBuf) Write(p []byte) (int, error) {
+	n, err := v.data.writeAt(p, v.ptr)
+	v.ptr += int64(n)
+	return n, err
+}
+
+// WriteAt writes len(p) bytes to the Buffer starting at byte offset off.
+// If off is beyond the end of the Buffer, the gap is left as a hole.
+// It returns the number of bytes written and an error if any.
+// WriteAt returns non-nil error when n!=len(p).
+func (v *ChunkedBuf) WriteAt(p []byte, off int64) (int, error) {
+	return v.data.writeAt(p, off)
+}
+
+// Close the buffer. Currently no effect.
+func (v *ChunkedBuf) Close() error {
+	return nil
+}
+
+// Read reads len(p) byte from the Buffer starting at the current offset.
+// It returns the number of bytes read and an error if any.
+// Returns io.EOF error if pointer is at the end of the Buffer.
+func (v *ChunkedBuf) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if v.ptr >= v.data.size {
+		return 0, io.EOF
+	}
+	n, _ = v.data.readAt(p, v.ptr)
+	v.ptr += int64(n)
+	return n, nil
+}
+
+// ReadAt reads len(b) bytes from the Buffer starting at byte offset off.
+// It returns the number of bytes read and the error, if any.
+// ReadAt always returns a non-nil error when n < len(b).
+// At end of file, that error is io.EOF.
+func (v *ChunkedBuf) ReadAt(p []byte, off int64) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return v.data.readAt(p, off)
+}
+
+// Truncate truncates the Buffer to a given size.
+// It returns an error if the given size is negative.
+// If the Buffer is larger than the specified size, the extra data is lost
+// and the chunks beyond the new size are released.
+// If the Buffer is smaller, it is extended by a hole reading as zero bytes.
+func (v *ChunkedBuf) Truncate(size int64) error {
+	if size < 0 {
+		return errors.New("Truncate: size must be non-negative")
+	}
+	v.data.truncate(size)
+	return nil
+}
+
+func (s *chunkStore) readAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("ReadAt: negative offset")
+	}
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	if rest := s.size - off; int64(len(p)) > rest {
+		p = p[:rest]
+		err = io.EOF
+	}
+	for n < len(p) {
+		idx, start := (off+int64(n))/s.chunkSize, (off+int64(n))%s.chunkSize
+		end := start + int64(len(p)-n)
+		if end > s.chunkSize {
+			end = s.chunkSize
+		}
+		if chunk, ok := s.chunks[idx]; ok {
+			copy(p[n:], chunk[start:end])
+		} else {
+			clear(p[n : n+int(end-start)])
+		}
+		n += int(end - start)
+	}
+	return n, err
+}
+
+func (s *chunkStore) writeAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("WriteAt: negative offset")
+	}
+	for n < len(p) {
+		idx, start := (off+int64(n))/s.chunkSize, (off+int64(n))%s.chunkSize
+		end := start + int64(len(p)-n)
+		if end > s.chunkSize {
+			end = s.chunkSize
+		}
+		part := p[n : n+int(end-start)]
+		chunk, ok := s.chunks[idx]
+		if !ok && !isZero(part) {
+			// Zeros written into a hole keep it a hole
+			if chunk, err = s.allocate(); err != nil {
+				break
+			}
+			s.chunks[idx] = chunk
+		}
+		if chunk != nil {
+			copy(chunk[start:end], part)
+		}
+		n += len(part)
+	}
+	if end := off + int64(n); end > s.size {
+		s.size = end
+	}
+	return n, err
+}
+
+func (s *chunkStore) truncate(size int64) {
+	if size < s.size {
+		for idx := range s.chunks {
+			if idx*s.chunkSize >= size {
+				delete(s.chunks, idx)
+				if s.reserve != nil {
+					s.reserve(-s.chunkSize)
+				}
+			}
+		}
+		// Zero the tail of the last chunk, so extending it again reads zeros
+		if chunk, ok := s.chunks[size/s.chunkSize]; ok {
+			clear(chunk[size%s.chunkSize:])
+		}
+	}
+	s.size = size
+}
+
+// allocate allocates a new chunk.
+func (s *chunkStore) allocate() ([]byte, error) {
+	if s.reserve != nil {
+		if err := s.reserve(s.chunkSize); err != nil {
+			return nil, err
+		}
+	}
+	chunk, err := makeSlice(int(s.chunkSize))
+	if err != nil && s.reserve != nil {
+		s.reserve(-s.chunkSize)
+	}
+	return chunk, err
+}
+
+// isZero reports whether p only consists of zero bytes.
+func isZero(p []byte) bool {
+	for _, b := range p {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
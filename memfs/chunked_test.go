@@ -0,0 +1,152 @@
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+)
+
+func TestChunkedWriteRead(t *testing.T) {
+	v := NewChunkedBuffer(4)
+
+	// Write across multiple chunks
+	if n, err := v.Write([]byte(dots + abc)); err != nil || n != len(dots+abc) {
+		t.Fatalf("Unexpected write result: %d %s", n, err)
+	}
+	if l := v.Len(); l != int64(len(dots+abc)) {
+		t.Errorf("Invalid length: %d", l)
+	}
+
+	p := make([]byte, len(dots+abc))
+	if n, err := v.ReadAt(p, 0); err != nil || string(p[:n]) != dots+abc {
+		t.Errorf("Unexpected read result: %d %s %q", n, err, p[:n])
+	}
+
+	// Read crossing the end
+	if n, err := v.ReadAt(p, 30); err != io.EOF || string(p[:n]) != abc[14:] {
+		t.Errorf("Unexpected read result: %d %s %q", n, err, p[:n])
+	}
+
+	// Sequential read
+	if _, err := v.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Unexpected seek error: %s", err)
+	}
+	if n, err := v.Read(p[:5]); err != nil || string(p[:n]) != dots[2:7] {
+		t.Errorf("Unexpected read result: %d %s %q", n, err, p[:n])
+	}
+}
+
+func TestChunkedSeekBeyondEnd(t *testing.T) {
+	v := NewChunkedBuffer(4)
+	v.Write([]byte("ab"))
+
+	if n, err := v.Seek(10, io.SeekEnd); err != nil || n != 12 {
+		t.Fatalf("Unexpected seek result: %d %s", n, err)
+	}
+	if _, err := v.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Expected negative position error")
+	}
+
+	// Reading beyond the end returns EOF and does not extend the buffer
+	p := make([]byte, 2)
+	if n, err := v.Read(p); err != io.EOF || n != 0 {
+		t.Errorf("Expected EOF: %d %s", n, err)
+	}
+	if l := v.Len(); l != 2 {
+		t.Errorf("Read extended the buffer: %d", l)
+	}
+
+	v.Write([]byte("cd"))
+	expected := append([]byte("ab"), make([]byte, 10)...)
+	expected = append(expected, "cd"...)
+	got := make([]byte, 20)
+	n, _ := v.ReadAt(got, 0)
+	if !bytes.Equal(got[:n], expected) {
+		t.Errorf("Invalid content with hole: %v", got[:n])
+	}
+	// Chunks 1 and 2 (bytes 4-11) are a hole and were never allocated
+	if a := v.Allocated(); a != 2*4 {
+		t.Errorf("Invalid allocation: %d", a)
+	}
+}
+
+func TestChunkedTruncate(t *testing.T) {
+	v := NewChunkedBuffer(4)
+	v.Write([]byte(dots))
+
+	// Growing allocates nothing
+	if err := v.Truncate(1 << 40); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if l, a := v.Len(), v.Allocated(); l != 1<<40 || a != int64(len(dots)) {
+		t.Errorf("Invalid length or allocation: %d %d", l, a)
+	}
+
+	// Shrinking releases chunks and zeroes the tail
+	if err := v.Truncate(5); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if a := v.Allocated(); a != 8 {
+		t.Errorf("Chunks not released: %d", a)
+	}
+	v.Truncate(8)
+	p := make([]byte, 8)
+	v.ReadAt(p, 0)
+	if !bytes.Equal(p, []byte{'1', '.', '.', '.', '.', 0, 0, 0}) {
+		t.Errorf("Invalid content after truncate: %q", p)
+	}
+
+	if err := v.Truncate(-1); err == nil {
+		t.Errorf("Expected error on negative size")
+	}
+}
+
+func TestChunkedZeroWriteKeepsHole(t *testing.T) {
+	v := NewChunkedBuffer(4)
+	v.Write(make([]byte, 16))
+	if l, a := v.Len(), v.Allocated(); l != 16 || a != 0 {
+		t.Errorf("Invalid length or allocation: %d %d", l, a)
+	}
+}
+
+func TestMemFSChunkedFiles(t *testing.T) {
+	fs := Create().WithChunkedBuffers(1024)
+
+	f, err := fs.OpenFile("/sparse", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := f.Truncate(2 << 30); err != nil {
+		t.Fatalf("Unexpected truncate error: %s", err)
+	}
+	if _, err := f.Seek(1<<30, io.SeekStart); err != nil {
+		t.Fatalf("Unexpected seek error: %s", err)
+	}
+	if _, err := f.Write([]byte(abc)); err != nil {
+		t.Fatalf("Unexpected write error: %s", err)
+	}
+
+	if fi, err := fs.Stat("/sparse"); err != nil || fi.Size() != 2<<30 {
+		t.Errorf("Invalid size: %v %s", fi, err)
+	}
+	p := make([]byte, len(abc)+2)
+	if _, err := f.ReadAt(p, 1<<30-1); err != nil {
+		t.Errorf("Unexpected read error: %s", err)
+	} else if string(p) != "\x00"+abc+"\x00" {
+		t.Errorf("Invalid content: %q", p)
+	}
+
+	// Other files and symlinks work as usual
+	if err := vfs.WriteFile(fs, "/file", []byte(dots), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Symlink("/file", "/link"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, err := vfs.ReadFile(fs, "/link"); err != nil || string(b) != dots {
+		t.Errorf("Invalid content: %q %s", b, err)
+	}
+}
@@ -0,0 +1,138 @@
+package memfs
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// lastID is the last id given to a node or a handle.
+var lastID atomic.Uint64
+
+func nextID() uint64 {
+	return lastID.Add(1)
+}
+
+// zeros is written for holes of chunked buffers.
+var zeros [4096]byte
+
+// ReadFrom reads data from r until EOF and writes it to the file starting
+// at the current offset, or at the end of the file if it was opened with
+// os.O_APPEND. The offset is advanced by the number of bytes written.
+// If r is a handle on another file of a MemFS, the content is copied
+// directly without an intermediate buffer.
+// It implements io.ReaderFrom, so it is used by io.Copy.
+func (b *MemFile) ReadFrom(r io.Reader) (int64, error) {
+	if src := memFileOf(r, true); canCopyDirect(b, src) {
+		return copyDirect(b, src)
+	}
+	return io.Copy(struct{ io.Writer }{b}, r)
+}
+
+// WriteTo writes the content of the file from the current offset to w.
+// The offset is advanced by the number of bytes written.
+// If w is a handle on another file of a MemFS, the content is copied
+// directly without an intermediate buffer.
+// It implements io.WriterTo, so it is used by io.Copy.
+func (b *MemFile) WriteTo(w io.Writer) (int64, error) {
+	if dst := memFileOf(w, false); canCopyDirect(dst, b) {
+		return copyDirect(dst, b)
+	}
+	return io.Copy(w, struct{ io.Reader }{b})
+}
+
+// memFileOf returns the MemFile behind a handle of a MemFS, if the handle
+// permits reading (read is set) or writing.
+func memFileOf(x any, read bool) *MemFile {
+	switch f := x.(type) {
+	case *MemFile:
+		return f
+	case *roFile:
+		if read {
+			m, _ := f.File.(*MemFile)
+			return m
+		}
+	case *woFile:
+		if !read {
+			m, _ := f.File.(*MemFile)
+			return m
+		}
+	}
+	return nil
+}
+
+// canCopyDirect reports whether copyDirect can copy between the handles.
+// Handles on the same file are copied through an intermediate buffer.
+func canCopyDirect(dst, src *MemFile) bool {
+	return dst != nil && src != nil && dst.node != nil && src.node != nil && dst.node != src.node
+}
+
+// copyDirect copies the content of src from its offset to dst.
+// The offsets and contents are locked in the order of their ids, so
+// concurrent copies in opposite directions do not deadlock.
+func copyDirect(dst, src *MemFile) (int64, error) {
+	first, second := dst, src
+	if second.id < first.id {
+		first, second = second, first
+	}
+	first.offMutex.Lock()
+	defer first.offMutex.Unlock()
+	second.offMutex.Lock()
+	defer second.offMutex.Unlock()
+
+	if dst.node.id < src.node.id {
+		dst.mutex.Lock()
+		src.mutex.RLock()
+	} else {
+		src.mutex.RLock()
+		dst.mutex.Lock()
+	}
+	defer dst.mutex.Unlock()
+	defer src.mutex.RUnlock()
+
+	off := dst.off
+	if dst.append {
+		off = dst.data.Len()
+	}
+	n, err := copyBuffer(dst.data, off, src.data, src.off)
+	src.touch()
+	dst.off = off + n
+	src.off += n
+	return n, err
+}
+
+// copyBuffer writes the content of src starting at srcOff to dst at off.
+func copyBuffer(dst io.WriterAt, off int64, src contentBuffer, srcOff int64) (int64, error) {
+	if srcOff >= src.Len() {
+		return 0, nil
+	}
+	switch s := src.(type) {
+	case *Buf:
+		n, err := dst.WriteAt((*s.buf)[srcOff:], off)
+		return int64(n), err
+	case *ChunkedBuf:
+		return s.data.writeTo(dst, off, srcOff)
+	}
+	return io.Copy(io.NewOffsetWriter(dst, off), io.NewSectionReader(src, srcOff, src.Len()-srcOff))
+}
+
+// writeTo writes the content starting at srcOff to dst at off chunk by chunk.
+// Holes are written as zeros.
+func (s *chunkStore) writeTo(dst io.WriterAt, off int64, srcOff int64) (n int64, err error) {
+	for pos := srcOff; pos < s.size; {
+		idx, start := pos/s.chunkSize, pos%s.chunkSize
+		end := min(s.chunkSize, start+s.size-pos)
+		var part []byte
+		if chunk, ok := s.chunks[idx]; ok {
+			part = chunk[start:end]
+		} else {
+			part = zeros[:min(end-start, int64(len(zeros)))]
+		}
+		m, err := dst.WriteAt(part, off+n)
+		n += int64(m)
+		pos += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,142 @@
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+)
+
+func TestCopyDirect(t *testing.T) {
+	for _, chunked := range []bool{false, true} {
+		fs := Create()
+		if chunked {
+			fs.WithChunkedBuffers(16)
+		}
+		content := strings.Repeat("0123456789", 10)
+		if err := vfs.WriteFile(fs, "/src", []byte(content), 0666); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		// A hole in a chunked buffer is copied as zeros
+		src, err := fs.OpenFile("/src", os.O_RDWR, 0666)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		src.Truncate(200)
+		src.Seek(5, io.SeekStart)
+
+		dst, err := fs.OpenFile("/dst", os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		dst.Write([]byte("abc"))
+
+		n, err := io.Copy(dst, src)
+		if err != nil || n != 195 {
+			t.Fatalf("Unexpected copy: %d %v", n, err)
+		}
+		b, _ := vfs.ReadFile(fs, "/dst")
+		expected := "abc" + content[5:] + string(make([]byte, 100))
+		if string(b) != expected {
+			t.Errorf("Invalid content (chunked %v): %q", chunked, b)
+		}
+
+		// Offsets of both handles are advanced
+		if pos, _ := src.Seek(0, io.SeekCurrent); pos != 200 {
+			t.Errorf("Unexpected source offset: %d", pos)
+		}
+		if pos, _ := dst.Seek(0, io.SeekCurrent); pos != 198 {
+			t.Errorf("Unexpected destination offset: %d", pos)
+		}
+	}
+}
+
+func TestCopyAppend(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/src", []byte("def"), 0666)
+	vfs.WriteFile(fs, "/dst", []byte("abc"), 0666)
+
+	src, _ := fs.Open("/src")
+	dst, err := fs.OpenFile("/dst", os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/dst"); string(b) != "abcdef" {
+		t.Errorf("Invalid content: %q", b)
+	}
+}
+
+func TestCopyWrappers(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/file", []byte("content"), 0666)
+
+	ro, _ := fs.Open("/file")
+	wo, _ := fs.OpenFile("/file", os.O_WRONLY, 0666)
+	for _, f := range []vfs.File{ro, wo} {
+		if _, ok := f.(io.ReaderFrom); !ok {
+			t.Errorf("%T does not implement io.ReaderFrom", f)
+		}
+		if _, ok := f.(io.WriterTo); !ok {
+			t.Errorf("%T does not implement io.WriterTo", f)
+		}
+	}
+
+	if _, err := ro.WriteAt([]byte("x"), 0); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly: %v", err)
+	}
+	if _, err := ro.(io.ReaderFrom).ReadFrom(strings.NewReader("x")); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly: %v", err)
+	}
+	if _, err := wo.ReadAt(make([]byte, 1), 0); err != ErrWriteOnly {
+		t.Errorf("Expected ErrWriteOnly: %v", err)
+	}
+	if _, err := wo.(io.WriterTo).WriteTo(io.Discard); err != ErrWriteOnly {
+		t.Errorf("Expected ErrWriteOnly: %v", err)
+	}
+
+	// Copies from and to other readers and writers
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, ro); err != nil || buf.String() != "content" {
+		t.Errorf("Unexpected copy: %q %v", buf.String(), err)
+	}
+	if _, err := io.Copy(wo, strings.NewReader("CONTENT!")); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/file"); string(b) != "CONTENT!" {
+		t.Errorf("Invalid content: %q", b)
+	}
+}
+
+func TestCopyConcurrentOpposite(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/a", bytes.Repeat([]byte{'a'}, 1000), 0666)
+	vfs.WriteFile(fs, "/b", bytes.Repeat([]byte{'b'}, 1000), 0666)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := "/a", "/b"
+			if i%2 == 1 {
+				from, to = to, from
+			}
+			for j := 0; j < 50; j++ {
+				src, _ := fs.Open(from)
+				dst, _ := fs.OpenFile(to, os.O_RDWR, 0666)
+				if _, err := io.Copy(dst, src); err != nil {
+					t.Errorf("Unexpected error: %s", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
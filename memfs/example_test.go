@@ -1,6 +1,8 @@
 package memfs_test
 
 import (
+	"fmt"
+
 	"github.com/lordofscripts/vfs/memfs"
 )
 
@@ -10,6 +12,28 @@ func ExampleMemFS() {
 	// Like every other vfs.Filesytem, it could be wrapped, e.g. read-only:
 	// fs = vfs.ReadOnly(fs)
 
-	// The memory fs is completely empty, permissions are supported (e.g. Stat()) but have no effect.
+	// The memory fs is completely empty, permissions are supported (e.g. Stat()) but
+	// only have an effect if enforced with fs.WithPermissions(uid, gid, umask).
 	fs.Mkdir("/tmp", 0777)
 }
+
+func ExampleFromTxtar() {
+	fs, err := memfs.FromTxtar([]byte(`
+-- src/main.go --
+package main
+-- bin/ mode=0700 --
+-- README -> src/main.go --
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	// Dump the tree, e.g. to compare it with a golden file
+	b, _ := fs.ToTxtar("/")
+	fmt.Print(string(b))
+	// Output:
+	// -- README -> src/main.go --
+	// -- bin/ mode=0700 --
+	// -- src/main.go --
+	// package main
+}
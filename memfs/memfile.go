@@ -1,91 +1,194 @@
 package memfs
 
 import (
+	"errors"
+	"io"
 	"sync"
 )
 
+// ErrAppendWriteAt is returned by WriteAt on files opened with os.O_APPEND.
+var ErrAppendWriteAt = errors.New("invalid use of WriteAt on file opened with O_APPEND")
+
 // MemFile represents a file backed by a Buffer which is secured from concurrent access.
+//
+// Every MemFile is a handle with its own offset on the shared content.
+// All methods are safe for concurrent use: the content is guarded by a
+// lock shared by all handles of the same file and the offset by a lock
+// owned by the handle. ReadAt and WriteAt do not use the offset and never
+// wait for operations depending on it.
 type MemFile struct {
-	Buffer
-	mutex *sync.RWMutex
-	name  string
+	data   contentBuffer // shared content, its own offset is not used
+	mutex  *sync.RWMutex // guards data, shared by all handles on data
+	name   string
+	append bool // writes always go to the end of the file
+
+	// node and tree are set for files of a MemFS, so Name follows renames
+	node *fileInfo
+	tree *sync.RWMutex
+
+	offMutex sync.Mutex // guards off
+	off      int64
+	id       uint64 // unique id, orders locking of multiple handles
 }
 
-// NewMemFile creates a Buffer which byte slice is safe from concurrent access,
-// the file itself is not thread-safe.
-//
-// This means multiple files can work safely on the same byte slice,
-// but multiple go routines working on the same file may corrupt the internal pointer structure.
+// NewMemFile creates a file handle on a byte slice which is safe from
+// concurrent access through rwMutex. Multiple handles may share the same
+// byte slice and mutex, each of them has its own offset.
 func NewMemFile(name string, rwMutex *sync.RWMutex, buf *[]byte) *MemFile {
+	return newMemFile(name, rwMutex, NewBuffer(buf), false)
+}
+
+func newMemFile(name string, rwMutex *sync.RWMutex, buf contentBuffer, appendMode bool) *MemFile {
 	return &MemFile{
-		Buffer: NewBuffer(buf),
+		data:   buf,
 		mutex:  rwMutex,
 		name:   name,
+		append: appendMode,
+		id:     nextID(),
 	}
 }
 
-// Name of the file
-func (b MemFile) Name() string {
-	return b.name
+// Name of the file.
+// For files of a MemFS it is the current path, even if the file was renamed.
+func (b *MemFile) Name() string {
+	if b.node == nil {
+		return b.name
+	}
+	b.tree.RLock()
+	defer b.tree.RUnlock()
+	return b.node.AbsPath()
 }
 
 // Sync has no effect
-func (b MemFile) Sync() error {
+func (b *MemFile) Sync() error {
 	return nil
 }
 
-// Truncate changes the size of the file
-func (b MemFile) Truncate(size int64) (err error) {
+// Close has no effect, the handle remains usable.
+func (b *MemFile) Close() error {
+	return nil
+}
+
+// Truncate changes the size of the file.
+// It does not change the offset of any handle.
+func (b *MemFile) Truncate(size int64) (err error) {
 	b.mutex.Lock()
-	err = b.Buffer.Truncate(size)
+	err = b.data.Truncate(size)
 	b.mutex.Unlock()
 	return
 }
 
-// Read reads len(p) byte from the underlying buffer starting at the current offset.
+// Read reads len(p) byte from the file starting at the current offset.
 // It returns the number of bytes read and an error if any.
-// Returns io.EOF error if pointer is at the end of the Buffer.
-// See Buf.Read()
+// Returns io.EOF error if the offset is at or beyond the end of the file.
 func (b *MemFile) Read(p []byte) (n int, err error) {
+	b.offMutex.Lock()
+	defer b.offMutex.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
 	b.mutex.RLock()
-	n, err = b.Buffer.Read(p)
+	n, err = b.data.ReadAt(p, b.off)
 	b.mutex.RUnlock()
+	b.touch()
+	b.off += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
 	return
 }
 
-// ReadAt reads len(b) bytes from the Buffer starting at byte offset off.
+// ReadAt reads len(b) bytes from the file starting at byte offset off.
 // It returns the number of bytes read and the error, if any.
 // ReadAt always returns a non-nil error when n < len(b).
 // At end of file, that error is io.EOF.
-// See Buf.ReadAt()
+// The offset of the handle is neither used nor changed.
 func (b *MemFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("ReadAt: negative offset")
+	}
 	b.mutex.RLock()
-	n, err = b.Buffer.ReadAt(p, off)
+	n, err = b.data.ReadAt(p, off)
 	b.mutex.RUnlock()
+	b.touch()
 	return
 }
 
-// Write writes len(p) byte to the Buffer.
+// touch updates the access time of the file of a MemFS.
+func (b *MemFile) touch() {
+	if b.node != nil {
+		b.node.touch()
+	}
+}
+
+// Write writes len(p) byte to the file starting at the current offset.
+// If the file was opened with os.O_APPEND, the data is atomically written
+// to the current end of the file instead.
+// If the offset is beyond the end of the file, the gap is filled with zeros.
 // It returns the number of bytes written and an error if any.
 // Write returns non-nil error when n!=len(p).
 func (b *MemFile) Write(p []byte) (n int, err error) {
+	b.offMutex.Lock()
+	defer b.offMutex.Unlock()
+
+	b.mutex.Lock()
+	if b.append {
+		b.off = b.data.Len()
+	}
+	n, err = b.data.WriteAt(p, b.off)
+	b.mutex.Unlock()
+	b.off += int64(n)
+	return
+}
+
+// WriteAt writes len(p) bytes to the file starting at byte offset off.
+// If off is beyond the end of the file, the gap is filled with zeros.
+// It returns the number of bytes written and an error, if any.
+// WriteAt returns a non-nil error when n != len(p).
+// The offset of the handle is neither used nor changed.
+func (b *MemFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if b.append {
+		return 0, ErrAppendWriteAt
+	}
+	if off < 0 {
+		return 0, errors.New("WriteAt: negative offset")
+	}
 	b.mutex.Lock()
-	n, err = b.Buffer.Write(p)
+	n, err = b.data.WriteAt(p, off)
 	b.mutex.Unlock()
 	return
 }
 
-// Seek sets the offset for the next Read or Write on the buffer to offset,
+// Seek sets the offset for the next Read or Write on the file to offset,
 // interpreted according to whence:
 //
 //	0 (os.SEEK_SET) means relative to the origin of the file
 //	1 (os.SEEK_CUR) means relative to the current offset
 //	2 (os.SEEK_END) means relative to the end of the file
 //
+// Seeking beyond the end of the file is allowed.
 // It returns the new offset and an error, if any.
-func (b *MemFile) Seek(offset int64, whence int) (n int64, err error) {
-	b.mutex.RLock()
-	n, err = b.Buffer.Seek(offset, whence)
-	b.mutex.RUnlock()
-	return
+func (b *MemFile) Seek(offset int64, whence int) (int64, error) {
+	b.offMutex.Lock()
+	defer b.offMutex.Unlock()
+
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = b.off + offset
+	case io.SeekEnd:
+		b.mutex.RLock()
+		abs = b.data.Len() + offset
+		b.mutex.RUnlock()
+	default:
+		return 0, errors.New("Seek: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("Seek: negative position")
+	}
+	b.off = abs
+	return abs, nil
 }
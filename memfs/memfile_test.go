@@ -1,6 +1,12 @@
 package memfs
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
 	"testing"
 
 	"github.com/lordofscripts/vfs"
@@ -9,3 +15,248 @@ import (
 func TestFileInterface(t *testing.T) {
 	_ = vfs.File(NewMemFile("", nil, nil))
 }
+
+func TestMemFileHandleOffsets(t *testing.T) {
+	buf := []byte("0123456789")
+	mutex := &sync.RWMutex{}
+	f1 := NewMemFile("/file", mutex, &buf)
+	f2 := NewMemFile("/file", mutex, &buf)
+
+	p := make([]byte, 4)
+	if _, err := f1.Read(p); err != nil || string(p) != "0123" {
+		t.Errorf("Unexpected read: %q %v", p, err)
+	}
+	// The second handle has its own offset
+	if _, err := f2.Read(p); err != nil || string(p) != "0123" {
+		t.Errorf("Unexpected read: %q %v", p, err)
+	}
+
+	// ReadAt and WriteAt neither use nor change the offset
+	if _, err := f1.WriteAt([]byte("ab"), 8); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := f1.ReadAt(p[:2], 8); err != nil || string(p[:2]) != "ab" {
+		t.Errorf("Unexpected read: %q %v", p[:2], err)
+	}
+	if pos, _ := f1.Seek(0, io.SeekCurrent); pos != 4 {
+		t.Errorf("Unexpected offset: %d", pos)
+	}
+
+	// Seeking beyond the end and writing fills the gap with zeros
+	if _, err := f2.Seek(12, io.SeekStart); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := f2.Write([]byte("x")); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s := string(buf); s != "01234567ab\x00\x00x" {
+		t.Errorf("Unexpected content: %q", s)
+	}
+}
+
+func TestMemFileAppend(t *testing.T) {
+	fs := Create()
+	if err := vfs.WriteFile(fs, "/file", []byte("abc"), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := fs.OpenFile("/file", os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	other, err := fs.OpenFile("/file", os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Appending ignores the offset and writes to the current end
+	f.Seek(0, io.SeekStart)
+	other.Write([]byte("XYZ"))
+	if _, err := f.Write([]byte("def")); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := f.WriteAt([]byte("g"), 0); err != ErrAppendWriteAt {
+		t.Errorf("Expected ErrAppendWriteAt: %v", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/file"); string(b) != "XYZdef" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+}
+
+func TestMemFileConcurrentHandle(t *testing.T) {
+	const workers, writes = 8, 200
+	buf := make([]byte, 0)
+	f := NewMemFile("/file", &sync.RWMutex{}, &buf)
+
+	// Writes on a shared handle never overlap
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if _, err := f.Write([]byte("ab")); err != nil {
+					t.Errorf("Unexpected error: %s", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s := string(buf); s != string(bytes.Repeat([]byte("ab"), workers*writes)) {
+		t.Errorf("Unexpected content of length %d", len(s))
+	}
+	if pos, _ := f.Seek(0, io.SeekCurrent); pos != 2*workers*writes {
+		t.Errorf("Unexpected offset: %d", pos)
+	}
+}
+
+func TestMemFileConcurrentAppend(t *testing.T) {
+	const workers, records = 8, 100
+	for _, fs := range []*MemFS{Create(), Create().WithChunkedBuffers(64)} {
+		if err := vfs.WriteFile(fs, "/log", nil, 0666); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				f, err := fs.OpenFile("/log", os.O_WRONLY|os.O_APPEND, 0666)
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+					return
+				}
+				record := bytes.Repeat([]byte{byte('a' + i)}, 10)
+				for j := 0; j < records; j++ {
+					if _, err := f.Write(record); err != nil {
+						t.Errorf("Unexpected error: %s", err)
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		b, err := vfs.ReadFile(fs, "/log")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(b) != workers*records*10 {
+			t.Fatalf("Unexpected length: %d", len(b))
+		}
+		// No record was interleaved with another one
+		for off := 0; off < len(b); off += 10 {
+			if rec := b[off : off+10]; !bytes.Equal(rec, bytes.Repeat(rec[:1], 10)) {
+				t.Fatalf("Interleaved record at %d: %q", off, rec)
+			}
+		}
+	}
+}
+
+func TestMemFileConcurrentWorkload(t *testing.T) {
+	const workers, rounds = 4, 100
+	fs := Create()
+	if err := vfs.MkdirAll(fs, "/dir", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	shared, err := fs.OpenFile("/dir/shared", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	run := func(f func(i, j int) error) {
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < rounds; j++ {
+					if err := f(i, j); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(i)
+		}
+	}
+
+	// Positional I/O on the shared handle
+	run(func(i, j int) error {
+		p := []byte(fmt.Sprintf("%04d", j))
+		if _, err := shared.WriteAt(p, int64(i*4)); err != nil {
+			return err
+		}
+		_, err := shared.ReadAt(p, int64(i*4))
+		return err
+	})
+	// Offset based I/O on the shared handle
+	run(func(i, j int) error {
+		if _, err := shared.Seek(int64(j), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := shared.Read(make([]byte, 8)); err != nil && err != io.EOF {
+			return err
+		}
+		_, err := shared.Write([]byte{byte(j)})
+		return err
+	})
+	// Truncation through other handles
+	run(func(i, j int) error {
+		f, err := fs.OpenFile("/dir/shared", os.O_RDWR, 0666)
+		if err != nil {
+			return err
+		}
+		return f.Truncate(int64(j % 32))
+	})
+	// Metadata and tree modifications
+	run(func(i, j int) error {
+		name := fmt.Sprintf("/dir/f%d", i)
+		if err := vfs.WriteFile(fs, name, []byte("content"), 0666); err != nil {
+			return err
+		}
+		if _, err := fs.Stat("/dir/shared"); err != nil {
+			return err
+		}
+		if _, err := fs.Stat("/dir"); err != nil {
+			return err
+		}
+		if _, err := fs.ReadDir("/dir"); err != nil {
+			return err
+		}
+		if err := fs.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	wg.Wait()
+}
+
+func TestStatSnapshot(t *testing.T) {
+	fs := Create()
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// Fresh directories have a size
+	if fi, err := fs.Stat("/dir"); err != nil || fi.Size() != 0 || !fi.IsDir() {
+		t.Errorf("Unexpected stat: %v %v", fi, err)
+	}
+
+	if err := vfs.WriteFile(fs, "/dir/file", []byte("abc"), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fi, err := fs.Stat("/dir/file")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/file", []byte("abcdef"), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Rename("/dir/file", "/dir/other"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if fi.Name() != "file" || fi.Size() != 3 {
+		t.Errorf("Snapshot changed: %s %d", fi.Name(), fi.Size())
+	}
+}
@@ -3,10 +3,13 @@ package memfs
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	filepath "path"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/lordofscripts/vfs"
@@ -28,81 +31,224 @@ const PathSeparator = "/"
 
 // MemFS is a in-memory filesystem
 type MemFS struct {
-	root *fileInfo
-	wd   *fileInfo
-	lock *sync.RWMutex
+	root      *fileInfo
+	wd        *fileInfo
+	lock      *sync.RWMutex
+	chunkSize int // chunk size of new files, 0 for contiguous buffers
+	quota     *quota
+	dev       uint64 // device number reported by vfs.SysInfo
+
+	// Permission enforcement, see WithPermissions
+	enforce  bool
+	uid, gid int
+	umask    os.FileMode
 }
 
 var _ vfs.Filesystem = &MemFS{}
 
 // Create a new MemFS filesystem which entirely resides in memory
 func Create() *MemFS {
-	root := &fileInfo{
-		name: "/",
-		dir:  true,
+	fs := &MemFS{
+		lock:  &sync.RWMutex{},
+		quota: &quota{},
+		dev:   vfs.NewDev(),
 	}
-	return &MemFS{
-		root: root,
-		wd:   root,
-		lock: &sync.RWMutex{},
+	fs.root = fs.newNode("/", nil, 0777, true)
+	fs.wd = fs.root
+	return fs
+}
+
+// WithChunkedBuffers makes files created from now on use a ChunkedBuf with
+// the given chunk size instead of a contiguous Buf. This suits filesystems
+// holding large or sparse files.
+// If chunkSize is not positive, DefaultChunkSize is used.
+func (fs *MemFS) WithChunkedBuffers(chunkSize int) *MemFS {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
 	}
+	fs.chunkSize = chunkSize
+	return fs
 }
 
+// fileInfo is a node of the filesystem tree.
+// The tree structure, names and metadata are guarded by MemFS.lock,
+// the content by the node's own mutex, which is shared with all open handles.
 type fileInfo struct {
 	name    string
 	dir     bool
 	mode    os.FileMode
 	parent  *fileInfo
-	size    int64
 	modTime time.Time
-	fs      vfs.Filesystem
-	childs  map[string]*fileInfo
+	ctime   time.Time    // last change of the metadata
+	btime   time.Time    // creation
+	atime   atomic.Int64 // last access in Unix nanoseconds, updated by readers
+	fs      *MemFS
+	childs  map[string]*fileInfo // non-nil for directories
+	uid     int
+	gid     int
 	buf     *[]byte
-	mutex   *sync.RWMutex
-}
+	chunks  *chunkStore
+	mutex   *sync.RWMutex // guards the content, never nil
+	id      uint64        // unique id, orders locking of multiple nodes
 
-func (fi fileInfo) Sys() any {
-	return fi.fs
+	quota     *quota // nil if the node was removed
+	allocated int64  // bytes allocated for the content
 }
 
-func (fi fileInfo) Size() int64 {
-	if fi.dir {
-		return 0
+// newNode creates a node owned by the acting user, the caller must hold
+// fs.lock if the filesystem is in use.
+func (fs *MemFS) newNode(name string, parent *fileInfo, mode os.FileMode, dir bool) *fileInfo {
+	now := time.Now()
+	fi := &fileInfo{
+		name:    name,
+		dir:     dir,
+		mode:    mode,
+		parent:  parent,
+		modTime: now,
+		ctime:   now,
+		btime:   now,
+		fs:      fs,
+		uid:     fs.uid,
+		gid:     fs.gid,
+		mutex:   &sync.RWMutex{},
+		id:      nextID(),
+		quota:   fs.quota,
 	}
-	fi.mutex.RLock()
-	l := len(*(fi.buf))
-	fi.mutex.RUnlock()
-	return int64(l)
+	fi.atime.Store(now.UnixNano())
+	if dir {
+		fi.childs = make(map[string]*fileInfo)
+	}
+	return fi
+}
+
+// touch updates the access time of the node.
+func (fi *fileInfo) touch() {
+	fi.atime.Store(time.Now().UnixNano())
+}
+
+// change updates the modification and change time of the node,
+// the caller must hold MemFS.lock.
+func (fi *fileInfo) change(now time.Time) {
+	fi.modTime = now
+	fi.ctime = now
 }
 
-func (fi fileInfo) IsDir() bool {
-	return fi.dir
+// fileStat is a snapshot of a node implementing os.FileInfo.
+// It does not change if the node is modified later on.
+// Sys returns a *vfs.SysInfo, whose Raw field is the MemFS.
+type fileStat struct {
+	name    string
+	dir     bool
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	sys     any
 }
 
-// ModTime returns the modification time.
+func (fs *fileStat) Name() string       { return fs.name }
+func (fs *fileStat) IsDir() bool        { return fs.dir }
+func (fs *fileStat) Mode() os.FileMode  { return fs.mode }
+func (fs *fileStat) Size() int64        { return fs.size }
+func (fs *fileStat) Sys() any           { return fs.sys }
+func (fs *fileStat) ModTime() time.Time { return fs.modTime }
+
+// stat returns a snapshot of the node, the caller must hold MemFS.lock.
 // Modification time is updated on:
 //   - Creation
 //   - Rename
 //   - Open (except with O_RDONLY)
-func (fi fileInfo) ModTime() time.Time {
-	return fi.modTime
-}
-
-func (fi fileInfo) Mode() os.FileMode {
-	return fi.mode
+//
+// The change time is additionally updated on Chmod, the access time on
+// reading the file or the directory.
+func (fi *fileInfo) stat() os.FileInfo {
+	nlink := uint64(1)
+	if fi.dir {
+		// The entry in the parent, "." and ".." of every subdirectory
+		nlink = 2
+		for _, c := range fi.childs {
+			if c.dir {
+				nlink++
+			}
+		}
+	}
+	st := &fileStat{
+		name:    fi.name,
+		dir:     fi.dir,
+		mode:    fi.mode,
+		modTime: fi.modTime,
+		sys: &vfs.SysInfo{
+			Dev:   fi.fs.dev,
+			Ino:   fi.id,
+			Nlink: nlink,
+			Uid:   fi.uid,
+			Gid:   fi.gid,
+			Atime: time.Unix(0, fi.atime.Load()),
+			Mtime: fi.modTime,
+			Ctime: fi.ctime,
+			Btime: fi.btime,
+			Raw:   fi.fs,
+		},
+	}
+	if !fi.dir {
+		fi.mutex.RLock()
+		st.size = fi.length()
+		fi.mutex.RUnlock()
+	}
+	return st
 }
 
-func (fi fileInfo) Name() string {
-	return fi.name
+// length returns the size of the content, the caller must hold fi.mutex.
+func (fi *fileInfo) length() int64 {
+	switch {
+	case fi.chunks != nil:
+		return fi.chunks.size
+	case fi.buf != nil:
+		return int64(len(*fi.buf))
+	}
+	return 0
 }
 
-func (fi fileInfo) AbsPath() string {
+// AbsPath returns the absolute path of the node, the caller must hold MemFS.lock.
+func (fi *fileInfo) AbsPath() string {
 	if fi.parent != nil {
 		return filepath.Join(fi.parent.AbsPath(), fi.name)
 	}
 	return "/"
 }
 
+// buffer returns a new Buffer with its own offset on the content of the file.
+func (fi *fileInfo) buffer() contentBuffer {
+	if fi.chunks != nil {
+		return newChunkedBuffer(fi.chunks)
+	}
+	b := NewBuffer(fi.buf)
+	b.reserve = fi.reserve
+	return b
+}
+
+// content returns a copy of the content of the file.
+func (fi *fileInfo) content() []byte {
+	if fi.buf == nil && fi.chunks == nil {
+		return nil
+	}
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
+	if fi.chunks != nil {
+		b := make([]byte, fi.chunks.size)
+		fi.chunks.readAt(b, 0)
+		return b
+	}
+	return append([]byte(nil), *fi.buf...)
+}
+
+// linkTarget returns the target of a symlink.
+func (fi *fileInfo) linkTarget() string {
+	return string(fi.content())
+}
+
 // PathSeparator returns the path separator
 func (fs *MemFS) PathSeparator() uint8 {
 	return '/'
@@ -121,16 +267,14 @@ func (fs *MemFS) Mkdir(name string, perm os.FileMode) error {
 	if fi != nil {
 		return &os.PathError{Op: "mkdir", Path: name, Err: fmt.Errorf("directory %q already exists", name)}
 	}
-
-	fi = &fileInfo{
-		name:    base,
-		dir:     true,
-		mode:    perm,
-		parent:  parent,
-		modTime: time.Now(),
-		fs:      fs,
+	if !fs.access(parent, permWrite|permExec) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
 	}
-	parent.childs[base] = fi
+	if err := fs.quota.addNode(); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	parent.childs[base] = fs.newNode(base, parent, fs.newMode(perm), true)
 	return nil
 }
 
@@ -172,13 +316,20 @@ func (fs *MemFS) ReadDir(path string) ([]os.FileInfo, error) {
 	if err != nil {
 		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
 	}
-	if fi == nil || !fi.dir {
+	if fi == nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrNotExist}
+	}
+	if !fi.dir {
 		return nil, &os.PathError{Op: "readdir", Path: path, Err: vfs.ErrNotDirectory}
 	}
+	if !fs.access(fi, permRead) {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrPermission}
+	}
+	fi.touch()
 
 	fis := make([]os.FileInfo, 0, len(fi.childs))
 	for _, e := range fi.childs {
-		fis = append(fis, e)
+		fis = append(fis, e.stat())
 	}
 	sort.Sort(byName(fis))
 	return fis, nil
@@ -188,7 +339,18 @@ func (fs *MemFS) fileInfo(path string) (parent *fileInfo, node *fileInfo, err er
 	return fs.relativeFileInfo(fs.wd, path)
 }
 
+// lfileInfo is like fileInfo, but does not follow a symlink in the last segment.
+func (fs *MemFS) lfileInfo(path string) (parent *fileInfo, node *fileInfo, err error) {
+	return fs.lookup(fs.wd, path, false)
+}
+
 func (fs *MemFS) relativeFileInfo(wd *fileInfo, path string) (parent *fileInfo, node *fileInfo, err error) {
+	return fs.lookup(wd, path, true)
+}
+
+// lookup resolves path relative to wd. Symlinks in the last segment are only
+// followed if follow is set.
+func (fs *MemFS) lookup(wd *fileInfo, path string, follow bool) (parent *fileInfo, node *fileInfo, err error) {
 	parent, segments := fs.dirSegments(wd, path)
 	// Shortcut for working directory and root
 	if len(segments) == 0 {
@@ -197,8 +359,8 @@ func (fs *MemFS) relativeFileInfo(wd *fileInfo, path string) (parent *fileInfo,
 
 	// Determine root to traverse
 	for _, seg := range segments[:len(segments)-1] {
-		if parent.childs == nil {
-			return nil, nil, os.ErrNotExist
+		if !fs.access(parent, permExec) {
+			return nil, nil, os.ErrPermission
 		}
 		entry, ok := parent.childs[seg]
 		if !ok {
@@ -208,7 +370,7 @@ func (fs *MemFS) relativeFileInfo(wd *fileInfo, path string) (parent *fileInfo,
 			parent = entry
 		} else if entry.mode&os.ModeSymlink != 0 {
 			// Look up interior symlink
-			_, parent, err = fs.relativeFileInfo(parent, string(*entry.buf))
+			_, parent, err = fs.relativeFileInfo(parent, entry.linkTarget())
 			if err != nil {
 				return nil, nil, err
 			}
@@ -222,15 +384,14 @@ func (fs *MemFS) relativeFileInfo(wd *fileInfo, path string) (parent *fileInfo,
 	}
 
 	lastSeg := segments[len(segments)-1]
-	if parent.childs != nil {
-		if node, ok := parent.childs[lastSeg]; ok {
-			if node.mode&os.ModeSymlink != 0 {
-				return fs.relativeFileInfo(parent, string(*node.buf))
-			}
-			return parent, node, nil
+	if !fs.access(parent, permExec) {
+		return nil, nil, os.ErrPermission
+	}
+	if node, ok := parent.childs[lastSeg]; ok {
+		if follow && node.mode&os.ModeSymlink != 0 {
+			return fs.relativeFileInfo(parent, node.linkTarget())
 		}
-	} else {
-		parent.childs = make(map[string]*fileInfo)
+		return parent, node, nil
 	}
 
 	return parent, nil, nil
@@ -279,14 +440,13 @@ func (fs *MemFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, er
 		if !hasFlag(os.O_CREATE, flag) {
 			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
 		}
-		fiNode = &fileInfo{
-			name:    base,
-			dir:     false,
-			mode:    perm,
-			parent:  fiParent,
-			modTime: time.Now(),
-			fs:      fs,
+		if !fs.access(fiParent, permWrite|permExec) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
 		}
+		if err := fs.quota.addNode(); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		fiNode = fs.newNode(base, fiParent, fs.newMode(perm), false)
 		fiParent.childs[base] = fiNode
 	} else { // file exists
 		if hasFlag(os.O_CREATE|os.O_EXCL, flag) {
@@ -295,24 +455,36 @@ func (fs *MemFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, er
 		if fiNode.dir {
 			return nil, &os.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
 		}
+		if !fs.access(fiNode, openAccess(flag)) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+		}
 	}
 
 	if !hasFlag(os.O_RDONLY, flag) {
-		fiNode.modTime = time.Now()
+		fiNode.change(time.Now())
 	}
-	return fiNode.file(flag)
+	return fiNode.file(fs, flag)
 }
 
-func (fi *fileInfo) file(flag int) (vfs.File, error) {
-	if fi.buf == nil || hasFlag(os.O_TRUNC, flag) {
-		buf := make([]byte, 0, MinBufferSize)
-		fi.buf = &buf
-		fi.mutex = &sync.RWMutex{}
-	}
-	var f vfs.File = NewMemFile(fi.AbsPath(), fi.mutex, fi.buf)
-	if hasFlag(os.O_APPEND, flag) {
-		f.Seek(0, os.SEEK_END)
+// file opens a handle on the node, the caller must hold fs.lock.
+func (fi *fileInfo) file(fs *MemFS, flag int) (vfs.File, error) {
+	if fi.buf == nil && fi.chunks == nil {
+		if fs.chunkSize > 0 {
+			fi.chunks = newChunkStore(fs.chunkSize)
+			fi.chunks.reserve = fi.reserve
+		} else {
+			buf := make([]byte, 0)
+			fi.buf = &buf
+		}
+	} else if hasFlag(os.O_TRUNC, flag) {
+		// Truncate in place, so all handles of the file see the change
+		fi.mutex.Lock()
+		fi.buffer().Truncate(0)
+		fi.mutex.Unlock()
 	}
+	mf := newMemFile(fi.AbsPath(), fi.mutex, fi.buffer(), hasFlag(os.O_APPEND, flag))
+	mf.node, mf.tree = fi, fs.lock
+	var f vfs.File = mf
 	if hasFlag(os.O_RDWR, flag) {
 		return f, nil
 	} else if hasFlag(os.O_WRONLY, flag) {
@@ -334,6 +506,26 @@ func (f *roFile) Write(p []byte) (n int, err error) {
 	return 0, ErrReadOnly
 }
 
+// WriteAt is disabled and returns ErrorReadOnly
+func (f *roFile) WriteAt(p []byte, off int64) (n int, err error) {
+	return 0, ErrReadOnly
+}
+
+// ReadFrom is disabled and returns ErrorReadOnly
+func (f *roFile) ReadFrom(r io.Reader) (n int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// Truncate is disabled and returns a *os.PathError wrapping os.ErrPermission
+func (f *roFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.Name(), Err: os.ErrPermission}
+}
+
+// WriteTo writes the remaining content of the file to w.
+func (f *roFile) WriteTo(w io.Writer) (n int64, err error) {
+	return f.File.(io.WriterTo).WriteTo(w)
+}
+
 // woFile wraps the given file and disables Read(..) operation.
 type woFile struct {
 	vfs.File
@@ -344,6 +536,21 @@ func (f *woFile) Read(p []byte) (n int, err error) {
 	return 0, ErrWriteOnly
 }
 
+// ReadAt is disabled and returns ErrorWroteOnly
+func (f *woFile) ReadAt(p []byte, off int64) (n int, err error) {
+	return 0, ErrWriteOnly
+}
+
+// ReadFrom reads from r until EOF and writes the data to the file.
+func (f *woFile) ReadFrom(r io.Reader) (n int64, err error) {
+	return f.File.(io.ReaderFrom).ReadFrom(r)
+}
+
+// WriteTo is disabled and returns ErrorWroteOnly
+func (f *woFile) WriteTo(w io.Writer) (n int64, err error) {
+	return 0, ErrWriteOnly
+}
+
 // Remove removes the named file or directory.
 // If there is an error, it will be of type *PathError.
 func (fs *MemFS) Remove(name string) error {
@@ -351,15 +558,19 @@ func (fs *MemFS) Remove(name string) error {
 	defer fs.lock.Unlock()
 
 	name = filepath.Clean(name)
-	fiParent, fiNode, err := fs.fileInfo(name)
+	fiParent, fiNode, err := fs.lfileInfo(name)
 	if err != nil {
 		return &os.PathError{Op: "remove", Path: name, Err: err}
 	}
 	if fiNode == nil {
 		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
 	}
+	if !fs.canModifyEntry(fiParent, fiNode) {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+	}
 
 	delete(fiParent.childs, fiNode.name)
+	fiNode.detach()
 	return nil
 }
 
@@ -367,40 +578,100 @@ func (fs *MemFS) RemoveAll(path string) error {
 	return vfs.RemoveAll(fs, path)
 }
 
-// Rename renames (moves) a file.
-// Handles to the oldpath persist but might return oldpath if Name() is called.
+// hasFileAncestor reports whether the closest existing ancestor of path is
+// not a directory, which makes its lookup fail with os.ErrNotExist.
+func (fs *MemFS) hasFileAncestor(path string) bool {
+	for dir := filepath.Dir(path); dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		if _, node, err := fs.fileInfo(dir); err == nil && node != nil {
+			return !node.dir
+		}
+	}
+	return false
+}
+
+// Rename renames (moves) oldpath to newpath like rename(2).
+// If newpath already exists, it is replaced atomically: a file can replace a
+// file and a directory can replace an empty directory.
+// Symlinks are renamed, not their targets.
+// Open handles keep working and report the new path on Name().
+// If there is an error, it will be of type *LinkError:
+//   - syscall.EISDIR if a file would replace a directory,
+//   - syscall.ENOTDIR if a directory would replace a file or a parent of
+//     newpath is not a directory,
+//   - syscall.ENOTEMPTY if the replaced directory is not empty,
+//   - syscall.EINVAL if a directory would be moved into its own subtree.
 func (fs *MemFS) Rename(oldpath, newpath string) error {
 	fs.lock.Lock()
 	defer fs.lock.Unlock()
 
-	// OldPath
 	oldpath = filepath.Clean(oldpath)
-	fiOldParent, fiOld, err := fs.fileInfo(oldpath)
+	newpath = filepath.Clean(newpath)
+	linkErr := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+
+	fiOldParent, fiOld, err := fs.lfileInfo(oldpath)
 	if err != nil {
-		return &os.PathError{Op: "rename", Path: oldpath, Err: err}
+		return linkErr(err)
 	}
 	if fiOld == nil {
-		return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+		return linkErr(os.ErrNotExist)
+	}
+	fiNewParent, fiNew, err := fs.lfileInfo(newpath)
+	if errors.Is(err, os.ErrNotExist) && fs.hasFileAncestor(newpath) {
+		return linkErr(syscall.ENOTDIR)
 	}
-
-	newpath = filepath.Clean(newpath)
-	fiNewParent, fiNew, err := fs.fileInfo(newpath)
 	if err != nil {
-		return &os.PathError{Op: "rename", Path: newpath, Err: err}
+		return linkErr(err)
+	}
+	if fiOld == fiNew {
+		return nil
+	}
+	if fiOld == fs.root || fiNewParent == nil {
+		return linkErr(syscall.EBUSY)
 	}
 
+	if fiOld.dir {
+		// Moving a directory into its own subtree would detach a cycle
+		for p := fiNewParent; p != nil; p = p.parent {
+			if p == fiOld {
+				return linkErr(syscall.EINVAL)
+			}
+		}
+	}
 	if fiNew != nil {
-		return &os.PathError{Op: "rename", Path: newpath, Err: os.ErrExist}
+		switch {
+		case fiOld.dir && !fiNew.dir:
+			return linkErr(syscall.ENOTDIR)
+		case !fiOld.dir && fiNew.dir:
+			return linkErr(syscall.EISDIR)
+		case fiNew.dir && len(fiNew.childs) > 0:
+			return linkErr(syscall.ENOTEMPTY)
+		}
+		if !fs.canModifyEntry(fiNewParent, fiNew) {
+			return linkErr(os.ErrPermission)
+		}
+	}
+	if !fs.canModifyEntry(fiOldParent, fiOld) || !fs.access(fiNewParent, permWrite|permExec) {
+		return linkErr(os.ErrPermission)
+	}
+	// Moving a directory updates its ".." entry
+	if fiOld.dir && fiOldParent != fiNewParent && !fs.access(fiOld, permWrite) {
+		return linkErr(os.ErrPermission)
 	}
-
-	newBase := filepath.Base(newpath)
 
 	// Relink
+	now := time.Now()
+	if fiNew != nil {
+		fiNew.detach()
+	}
 	delete(fiOldParent.childs, fiOld.name)
 	fiOld.parent = fiNewParent
-	fiOld.name = newBase
-	fiOld.modTime = time.Now()
+	fiOld.name = filepath.Base(newpath)
+	fiOld.change(now)
 	fiNewParent.childs[fiOld.name] = fiOld
+	fiOldParent.change(now)
+	fiNewParent.change(now)
 	return nil
 }
 
@@ -419,12 +690,44 @@ func (fs *MemFS) Stat(name string) (os.FileInfo, error) {
 	if fi == nil {
 		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
 	}
-	return fi, nil
+	return fi.stat(), nil
 }
 
 // Lstat returns a FileInfo describing the named file.
-// MemFS does not support symbolic links.
-// Alias for fs.Stat(name)
+// If the file is a symbolic link, the returned FileInfo
+// describes the symbolic link. Lstat makes no attempt to follow the link.
+// If there is an error, it will be of type *PathError.
 func (fs *MemFS) Lstat(name string) (os.FileInfo, error) {
-	return fs.Stat(name)
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = filepath.Clean(name)
+	_, fi, err := fs.lfileInfo(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	if fi == nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return fi.stat(), nil
+}
+
+// Readlink returns the destination of the named symbolic link.
+// If there is an error, it will be of type *PathError.
+func (fs *MemFS) Readlink(name string) (string, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = filepath.Clean(name)
+	_, fi, err := fs.lfileInfo(name)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	if fi == nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+	if fi.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return fi.linkTarget(), nil
 }
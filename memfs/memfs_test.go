@@ -1,9 +1,11 @@
 package memfs
 
 import (
+	"errors"
 	"io"
 	"os"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -273,8 +275,8 @@ func TestReadDir(t *testing.T) {
 	}
 
 	// Readdir non existing directory
-	if _, err := fs.ReadDir("/usr"); err == nil {
-		t.Errorf("Expected error readdir(nofound)")
+	if _, err := fs.ReadDir("/usr"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error readdir(nofound): %v", err)
 	}
 }
 
@@ -324,6 +326,28 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestRemoveSymlink(t *testing.T) {
+	fs := Create()
+	if err := vfs.WriteFile(fs, "/file", []byte("data"), 0666); err != nil {
+		t.Fatalf("WriteFile error: %s", err)
+	}
+	fs.Symlink("/file", "/link")
+	fs.Symlink("/missing", "/dangling")
+
+	// The links are removed, not their targets
+	for _, name := range []string{"/link", "/dangling"} {
+		if err := fs.Remove(name); err != nil {
+			t.Errorf("Remove failed: %s", err)
+		}
+		if _, err := fs.Lstat(name); !os.IsNotExist(err) {
+			t.Errorf("Link %s not removed: %v", name, err)
+		}
+	}
+	if _, err := fs.Stat("/file"); err != nil {
+		t.Errorf("Target removed: %s", err)
+	}
+}
+
 func TestReadWrite(t *testing.T) {
 	fs := Create()
 	f, err := fs.OpenFile("/readme.txt", os.O_CREATE|os.O_RDWR, 0666)
@@ -616,6 +640,84 @@ func readFile(fs vfs.Filesystem, name string) ([]byte, error) {
 	return io.ReadAll(f)
 }
 
+func TestSysInfo(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"dir/a/":           "",
+		"dir/b/":           "",
+		"dir/file":         "content",
+		"dir/other":        "",
+		"link -> dir/file": "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	stat := func(name string) *vfs.SysInfo {
+		t.Helper()
+		fi, err := fs.Lstat(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		sys, ok := fi.Sys().(*vfs.SysInfo)
+		if !ok {
+			t.Fatalf("Expected *vfs.SysInfo: %T", fi.Sys())
+		}
+		return sys
+	}
+
+	file, other, dir := stat("/dir/file"), stat("/dir/other"), stat("/dir")
+	if file.Ino == 0 || file.Ino == other.Ino || file.Dev != other.Dev {
+		t.Errorf("Invalid identities: %+v %+v", file, other)
+	}
+	if root, _ := Create().Stat("/"); vfs.SysInfoOf(root).Dev == file.Dev {
+		t.Errorf("Devices of different filesystems are equal")
+	}
+	if file.Nlink != 1 || dir.Nlink != 4 {
+		t.Errorf("Invalid link counts: %d %d", file.Nlink, dir.Nlink)
+	}
+	if file.Raw != fs || file.Btime.IsZero() || !file.Mtime.Equal(file.Ctime) {
+		t.Errorf("Invalid SysInfo: %+v", file)
+	}
+
+	// Reading updates the access time, chmod the change time
+	time.Sleep(time.Millisecond)
+	if _, err := vfs.ReadFile(fs, "/dir/file"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Chmod("/dir/file", 0600); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s := stat("/dir/file"); !s.Atime.After(file.Atime) || !s.Ctime.After(file.Ctime) || !s.Mtime.Equal(file.Mtime) {
+		t.Errorf("Invalid timestamps: %+v", s)
+	}
+
+	// Identity survives renames
+	if err := fs.Rename("/dir/file", "/dir/renamed"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s := stat("/dir/renamed"); s.Ino != file.Ino {
+		t.Errorf("Ino changed on rename: %d %d", s.Ino, file.Ino)
+	}
+}
+
+func TestSameFile(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"dir/file":    "content",
+		"dir/other":   "content",
+		"link -> dir": "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if same, err := vfs.SameFile(fs, "/dir/file", "/link/file"); err != nil || !same {
+		t.Errorf("Expected same file: %v %v", same, err)
+	}
+	if same, err := vfs.SameFile(fs, "/dir/file", "/dir/other"); err != nil || same {
+		t.Errorf("Expected different files: %v %v", same, err)
+	}
+}
+
 func TestRename(t *testing.T) {
 	const content = "read me"
 	fs := Create()
@@ -669,8 +771,136 @@ func TestRename(t *testing.T) {
 	}
 
 	// Overwrite existing file
-	if err := fs.Rename("/newdirectory/README.txt", "/README.txt"); err == nil {
-		t.Errorf("Expected error renaming file")
+	if err := fs.Rename("/newdirectory/README.txt", "/README.txt"); err != nil {
+		t.Errorf("Unexpected error replacing file: %s", err)
+	}
+	if _, err := fs.Stat("/newdirectory/README.txt"); !os.IsNotExist(err) {
+		t.Errorf("Old file still exists")
+	}
+}
+
+func TestRenameReplace(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"file":      "file",
+		"other":     "other",
+		"dir/sub/x": "x",
+		"empty/":    "",
+		"empty2/":   "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		oldpath, newpath string
+		err              error
+	}{
+		{"/file", "/empty", syscall.EISDIR},
+		{"/empty", "/file", syscall.ENOTDIR},
+		{"/empty", "/dir", syscall.ENOTEMPTY},
+		{"/dir", "/dir/sub/dir", syscall.EINVAL},
+		{"/dir", "/dir/sub", syscall.EINVAL},
+		{"/", "/root", syscall.EBUSY},
+		{"/nonexisting", "/file", os.ErrNotExist},
+		{"/other", "/file/other", syscall.ENOTDIR},
+		{"/other", "/file/sub/other", syscall.ENOTDIR},
+		{"/other", "/nonexisting/other", os.ErrNotExist},
+	}
+	for _, test := range tests {
+		err := fs.Rename(test.oldpath, test.newpath)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Rename %s to %s: expected %v, got %v", test.oldpath, test.newpath, test.err, err)
+		}
+		if _, ok := err.(*os.LinkError); !ok {
+			t.Errorf("Rename %s to %s: expected *os.LinkError, got %T", test.oldpath, test.newpath, err)
+		}
+	}
+
+	// Renaming to itself is a no-op
+	if err := fs.Rename("/file", "/file"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// A file replaces a file, a directory replaces an empty directory
+	if err := fs.Rename("/other", "/file"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/file"); string(b) != "other" {
+		t.Errorf("File was not replaced: %q", b)
+	}
+	if err := fs.Rename("/dir", "/empty"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := fs.Stat("/empty/sub/x"); err != nil {
+		t.Errorf("Directory was not replaced: %s", err)
+	}
+	if u, _ := fs.Usage("/"); u.Nodes() != 5 {
+		t.Errorf("Replaced nodes were not released: %+v", u)
+	}
+}
+
+func TestRenameSymlink(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"dir/file":    "content",
+		"link -> dir": "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The link is renamed, not its target
+	if err := fs.Rename("/link", "/link2"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := fs.Stat("/dir/file"); err != nil {
+		t.Errorf("Target was moved: %s", err)
+	}
+	if _, err := fs.Stat("/link2/file"); err != nil {
+		t.Errorf("Link was not renamed: %s", err)
+	}
+}
+
+func TestRenameOpenHandle(t *testing.T) {
+	fs := Create()
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := fs.OpenFile("/dir/file", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ro, err := fs.Open("/dir/file")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	fi, _ := fs.Stat("/dir")
+	before := fi.ModTime()
+	time.Sleep(time.Millisecond)
+
+	if err := fs.Rename("/dir/file", "/file"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Rename("/dir", "/moved"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Rename("/file", "/moved/renamed"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, h := range []vfs.File{f, ro} {
+		if name := h.Name(); name != "/moved/renamed" {
+			t.Errorf("Wrong name after rename: %s", name)
+		}
+	}
+	if _, err := f.Write([]byte("abc")); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/moved/renamed"); string(b) != "abc" {
+		t.Errorf("Invalid content: %q", b)
+	}
+	if fi, _ := fs.Stat("/moved"); !fi.ModTime().After(before) {
+		t.Errorf("Parent modtime not updated")
 	}
 }
 
@@ -697,3 +927,82 @@ func TestModTime(t *testing.T) {
 		t.Error("Open with O_RDONLY should not modify mtime")
 	}
 }
+
+func TestReadlink(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"dir/file":         "content",
+		"link -> dir/file": "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if target, err := fs.Readlink("/link"); err != nil || target != "dir/file" {
+		t.Errorf("Unexpected target: %q %v", target, err)
+	}
+	if _, err := fs.Readlink("/dir/file"); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Expected EINVAL: %v", err)
+	}
+	if _, err := fs.Readlink("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist: %v", err)
+	}
+
+	// Lstat describes the link itself
+	fi, err := fs.Lstat("/link")
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 || fi.Name() != "link" {
+		t.Errorf("Expected symlink: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat("/link"); err != nil || fi.Mode()&os.ModeSymlink != 0 || fi.Size() != 7 {
+		t.Errorf("Expected target file: %v %v", fi, err)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	factory, ok := vfs.LookupBackend("mem")
+	if !ok {
+		t.Fatalf("Backend not registered")
+	}
+	fs, root, err := factory("", map[string]string{"chunk": "16", "maxnodes": "1"})
+	if err != nil || root != "/" {
+		t.Fatalf("Unexpected backend: %q %v", root, err)
+	}
+	mfs := fs.(*MemFS)
+	if mfs.chunkSize != 16 {
+		t.Errorf("Expected chunked buffers: %d", mfs.chunkSize)
+	}
+	vfs.WriteFile(mfs, "/a", nil, 0666)
+	if err := vfs.WriteFile(mfs, "/b", nil, 0666); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("Expected ErrNoSpace: %v", err)
+	}
+
+	for _, opts := range []map[string]string{
+		{"chunk": "x"}, {"maxbytes": ""}, {"unknown": ""},
+		{"chunk": "-5"}, {"chunk": "0"}, {"maxbytes": "-1"}, {"maxnodes": "0"},
+	} {
+		if _, _, err := factory("", opts); err == nil {
+			t.Errorf("Expected error for %v", opts)
+		}
+	}
+	if _, _, err := factory("/nonexisting.txtar", nil); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist: %v", err)
+	}
+
+	// The options apply to the fixture
+	source := t.TempDir() + "/fixture.txtar"
+	if err := vfs.WriteFile(vfs.OS(), source, []byte("-- a --\nA\n-- b --\nB\n"), 0666); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fs, _, err = factory(source, map[string]string{"chunk": "16"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, fi, _ := fs.(*MemFS).fileInfo("/a"); fi == nil || fi.chunks == nil {
+		t.Errorf("Expected chunked buffer for the fixture: %v", fi)
+	}
+	if _, _, err := factory(source, map[string]string{"maxnodes": "1"}); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("Expected ErrNoSpace: %v", err)
+	}
+	if _, _, err := factory(source, map[string]string{"chunk": "x", "maxnodes": "1"}); err == nil || errors.Is(err, ErrNoSpace) {
+		t.Errorf("Expected invalid option: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package memfs
+
+import (
+	"os"
+	filepath "path"
+	"time"
+)
+
+// Access bits checked against the permission bits of a node.
+const (
+	permExec  os.FileMode = 01
+	permWrite os.FileMode = 02
+	permRead  os.FileMode = 04
+)
+
+// WithPermissions enables the enforcement of permission bits for the given
+// acting user and group id. The umask is applied to the permissions of new
+// files and directories. The method may be called again to switch the
+// acting user.
+//
+// If enforced, like on a POSIX system:
+//   - opening a file requires read and/or write permission depending on the flags,
+//   - looking up a path requires search (execute) permission on every directory,
+//   - listing a directory requires read permission,
+//   - creating, removing and renaming requires write and search permission
+//     on the parent directory,
+//   - in a directory with the sticky bit set, only the owner of an entry or of
+//     the directory can remove or rename the entry.
+//
+// Denied operations return an *os.PathError wrapping os.ErrPermission.
+// The user id 0 (root) bypasses all checks.
+// New nodes are owned by the acting user and group.
+func (fs *MemFS) WithPermissions(uid, gid int, umask os.FileMode) *MemFS {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	fs.enforce = true
+	fs.uid = uid
+	fs.gid = gid
+	fs.umask = umask.Perm()
+	return fs
+}
+
+// access reports whether the acting user is granted the access bits want
+// on the node. Without enforcement all access is granted.
+func (fs *MemFS) access(fi *fileInfo, want os.FileMode) bool {
+	if !fs.enforce || fs.uid == 0 {
+		return true
+	}
+	perm := fi.mode.Perm()
+	switch {
+	case fi.uid == fs.uid:
+		perm >>= 6
+	case fi.gid == fs.gid:
+		perm >>= 3
+	}
+	return perm&want == want
+}
+
+// canModifyEntry reports whether the acting user may remove, rename or
+// replace the entry fi in the directory parent.
+func (fs *MemFS) canModifyEntry(parent, fi *fileInfo) bool {
+	if !fs.access(parent, permWrite|permExec) {
+		return false
+	}
+	if fs.enforce && fs.uid != 0 && parent.mode&os.ModeSticky != 0 {
+		return fs.uid == fi.uid || fs.uid == parent.uid
+	}
+	return true
+}
+
+// openAccess returns the access bits needed to open a file with flag.
+func openAccess(flag int) os.FileMode {
+	var want os.FileMode
+	switch {
+	case hasFlag(os.O_RDWR, flag):
+		want = permRead | permWrite
+	case hasFlag(os.O_WRONLY, flag):
+		want = permWrite
+	default:
+		want = permRead
+	}
+	if hasFlag(os.O_TRUNC, flag) || hasFlag(os.O_APPEND, flag) {
+		want |= permWrite
+	}
+	return want
+}
+
+// newMode applies the umask to the permissions of a new node.
+// Symlinks are always created with all permissions.
+func (fs *MemFS) newMode(perm os.FileMode) os.FileMode {
+	if perm&os.ModeSymlink != 0 {
+		return perm
+	}
+	return perm &^ fs.umask
+}
+
+// Chmod changes the mode of the named file to mode.
+// If the file is a symbolic link, it changes the mode of the link's target.
+// Only the permission bits, os.ModeSetuid, os.ModeSetgid and os.ModeSticky
+// are changed. If permissions are enforced, only the owner may change the mode.
+// If there is an error, it will be of type *PathError.
+func (fs *MemFS) Chmod(name string, mode os.FileMode) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	name = filepath.Clean(name)
+	_, fi, err := fs.fileInfo(name)
+	if err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	if fi == nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+	}
+	if fs.enforce && fs.uid != 0 && fs.uid != fi.uid {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrPermission}
+	}
+
+	const changeable = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	fi.mode = fi.mode&^changeable | mode&changeable
+	fi.ctime = time.Now()
+	return nil
+}
+
+// Chtimes changes the access and modification times of the named file,
+// like os.Chtimes. A zero time.Time value leaves the time unchanged.
+// If the file is a symbolic link, it changes the times of the link's target.
+// If permissions are enforced, only the owner may change the times.
+// If there is an error, it will be of type *PathError.
+func (fs *MemFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	name = filepath.Clean(name)
+	_, fi, err := fs.fileInfo(name)
+	if err != nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: err}
+	}
+	if fi == nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrNotExist}
+	}
+	if fs.enforce && fs.uid != 0 && fs.uid != fi.uid {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrPermission}
+	}
+
+	if !atime.IsZero() {
+		fi.atime.Store(atime.UnixNano())
+	}
+	if !mtime.IsZero() {
+		fi.modTime = mtime
+	}
+	fi.ctime = time.Now()
+	return nil
+}
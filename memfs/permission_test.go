@@ -0,0 +1,212 @@
+package memfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lordofscripts/vfs"
+)
+
+const (
+	alice = 1000
+	bob   = 1001
+	staff = 100
+)
+
+func assertPermission(t *testing.T, err error, op string) {
+	t.Helper()
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("%s: expected permission error, got: %v", op, err)
+		return
+	}
+	switch err.(type) {
+	case *os.PathError, *os.LinkError:
+	default:
+		t.Errorf("%s: expected *os.PathError or *os.LinkError, got: %T", op, err)
+	}
+}
+
+func TestPermissionsNotEnforced(t *testing.T) {
+	fs := Create()
+	if err := vfs.WriteFile(fs, "/file", []byte(dots), 0400); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := fs.OpenFile("/file", os.O_RDWR, 0); err != nil {
+		t.Errorf("Unexpected error without enforcement: %s", err)
+	}
+}
+
+func TestPermissionsOpen(t *testing.T) {
+	fs := Create().WithPermissions(alice, staff, 0)
+	if err := vfs.WriteFile(fs, "/readonly", []byte(dots), 0400); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/writeonly", []byte(dots), 0200); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if _, err := fs.OpenFile("/readonly", os.O_RDONLY, 0); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	_, err := fs.OpenFile("/readonly", os.O_RDWR, 0)
+	assertPermission(t, err, "open O_RDWR")
+	_, err = fs.OpenFile("/readonly", os.O_WRONLY|os.O_TRUNC, 0)
+	assertPermission(t, err, "open O_TRUNC")
+	_, err = fs.OpenFile("/writeonly", os.O_RDONLY, 0)
+	assertPermission(t, err, "open O_RDONLY")
+	if _, err := fs.OpenFile("/writeonly", os.O_WRONLY, 0); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// Group and other bits apply to other users
+	fs.WithPermissions(bob, staff, 0)
+	if err := fs.Chmod("/readonly", 0444); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Only the owner may chmod: %v", err)
+	}
+	fs.WithPermissions(alice, staff, 0)
+	if err := fs.Chmod("/readonly", 0640); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fs.WithPermissions(bob, staff, 0)
+	if _, err := fs.OpenFile("/readonly", os.O_RDONLY, 0); err != nil {
+		t.Errorf("Group should be able to read: %s", err)
+	}
+	fs.WithPermissions(bob, bob, 0)
+	_, err = fs.OpenFile("/readonly", os.O_RDONLY, 0)
+	assertPermission(t, err, "open as other")
+
+	// Root bypasses all checks
+	fs.WithPermissions(0, 0, 0)
+	if _, err := fs.OpenFile("/readonly", os.O_RDWR, 0); err != nil {
+		t.Errorf("Unexpected error as root: %s", err)
+	}
+}
+
+func TestPermissionsTruncateReadOnly(t *testing.T) {
+	fs := Create().WithPermissions(alice, staff, 022)
+	if err := vfs.WriteFile(fs, "/file", []byte(dots), 0644); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Chmod("/file", 0444); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := fs.OpenFile("/file", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer f.Close()
+	assertPermission(t, f.Truncate(0), "truncate read-only handle")
+	if data, err := vfs.ReadFile(fs, "/file"); err != nil || string(data) != dots {
+		t.Errorf("Content changed: %q, %v", data, err)
+	}
+}
+
+func TestPermissionsDirectories(t *testing.T) {
+	fs := Create().WithPermissions(alice, staff, 0)
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/file", []byte(dots), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// No write permission: no new children, no removal, no rename
+	fs.Chmod("/dir", 0500)
+	assertPermission(t, fs.Mkdir("/dir/sub", 0777), "mkdir")
+	_, err := fs.OpenFile("/dir/new", os.O_CREATE|os.O_WRONLY, 0666)
+	assertPermission(t, err, "create")
+	assertPermission(t, fs.Remove("/dir/file"), "remove")
+	assertPermission(t, fs.Rename("/dir/file", "/file"), "rename")
+	if _, err := vfs.ReadFile(fs, "/dir/file"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// No read permission: no listing
+	fs.Chmod("/dir", 0300)
+	_, err = fs.ReadDir("/dir")
+	assertPermission(t, err, "readdir")
+	if _, err := fs.Stat("/dir/file"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// No search permission: no traversal
+	fs.Chmod("/dir", 0600)
+	_, err = fs.Stat("/dir/file")
+	assertPermission(t, err, "stat")
+	_, err = fs.OpenFile("/dir/file", os.O_RDONLY, 0)
+	assertPermission(t, err, "open")
+}
+
+func TestPermissionsUmask(t *testing.T) {
+	fs := Create().WithPermissions(alice, staff, 0022)
+	if err := vfs.WriteFile(fs, "/file", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if fi, _ := fs.Stat("/file"); fi.Mode() != 0644 {
+		t.Errorf("Invalid file mode: %s", fi.Mode())
+	}
+	if fi, _ := fs.Stat("/dir"); fi.Mode().Perm() != 0755 {
+		t.Errorf("Invalid directory mode: %s", fi.Mode())
+	}
+}
+
+func TestPermissionsSticky(t *testing.T) {
+	fs := Create().WithPermissions(0, 0, 0)
+	if err := fs.Mkdir("/tmp", 0777|os.ModeSticky); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	fs.WithPermissions(alice, staff, 0)
+	if err := vfs.WriteFile(fs, "/tmp/alice", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	fs.WithPermissions(bob, staff, 0)
+	if err := vfs.WriteFile(fs, "/tmp/bob", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertPermission(t, fs.Remove("/tmp/alice"), "remove foreign file")
+	assertPermission(t, fs.Rename("/tmp/alice", "/tmp/stolen"), "rename foreign file")
+	if err := fs.Rename("/tmp/bob", "/tmp/bob2"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := fs.Remove("/tmp/bob2"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	fs.WithPermissions(alice, staff, 0)
+	if err := fs.Remove("/tmp/alice"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestChtimes(t *testing.T) {
+	fs := Create().WithPermissions(alice, staff, 0)
+	vfs.WriteFile(fs, "/file", nil, 0666)
+
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := atime.Add(time.Hour)
+	if err := fs.Chtimes("/file", atime, mtime); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fi, _ := fs.Stat("/file")
+	if !fi.ModTime().Equal(mtime) || !vfs.SysInfoOf(fi).Atime.Equal(atime) {
+		t.Errorf("Unexpected times: %v %v", fi.ModTime(), vfs.SysInfoOf(fi).Atime)
+	}
+
+	// Zero times are left unchanged
+	if err := fs.Chtimes("/file", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if fi, _ := fs.Stat("/file"); !fi.ModTime().Equal(mtime) {
+		t.Errorf("Unexpected modification time: %v", fi.ModTime())
+	}
+
+	fs.WithPermissions(bob, staff, 0)
+	assertPermission(t, fs.Chtimes("/file", atime, mtime), "chtimes")
+}
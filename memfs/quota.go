@@ -0,0 +1,160 @@
+package memfs
+
+import (
+	"os"
+	filepath "path"
+	"sync"
+	"syscall"
+)
+
+// ErrNoSpace is returned if an operation exceeds the byte budget or the
+// maximum node count of a MemFS. It is syscall.ENOSPC, so it can be tested
+// with errors.Is(err, syscall.ENOSPC) as on a real filesystem.
+var ErrNoSpace error = syscall.ENOSPC
+
+// quota tracks the memory and nodes used by a MemFS and enforces its limits.
+type quota struct {
+	mutex    sync.Mutex
+	maxBytes int64 // 0 means unlimited
+	maxNodes int64 // 0 means unlimited
+	bytes    int64 // allocated bytes
+	nodes    int64 // number of nodes, the root is not counted
+}
+
+// reserve accounts delta allocated bytes, a negative delta releases bytes.
+// It returns ErrNoSpace if the byte budget would be exceeded.
+func (q *quota) reserve(delta int64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if delta > 0 && q.maxBytes > 0 && q.bytes+delta > q.maxBytes {
+		return ErrNoSpace
+	}
+	q.bytes += delta
+	return nil
+}
+
+// addNode accounts a new node.
+// It returns ErrNoSpace if the maximum node count would be exceeded.
+func (q *quota) addNode() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.maxNodes > 0 && q.nodes+1 > q.maxNodes {
+		return ErrNoSpace
+	}
+	q.nodes++
+	return nil
+}
+
+// release releases nodes and allocated bytes.
+func (q *quota) release(nodes, bytes int64) {
+	q.mutex.Lock()
+	q.nodes -= nodes
+	q.bytes -= bytes
+	q.mutex.Unlock()
+}
+
+// WithLimits bounds the memory footprint of the filesystem to maxBytes
+// allocated bytes and maxNodes files, directories and symlinks.
+// A limit of 0 means unlimited.
+//
+// The byte budget covers the allocated capacity of file buffers, including
+// the slack kept for growth. If the slack does not fit into the budget, a
+// buffer grows to the exact size needed instead.
+// Operations exceeding a limit fail with ErrNoSpace.
+func (fs *MemFS) WithLimits(maxBytes, maxNodes int64) *MemFS {
+	fs.quota.mutex.Lock()
+	defer fs.quota.mutex.Unlock()
+
+	fs.quota.maxBytes = maxBytes
+	fs.quota.maxNodes = maxNodes
+	return fs
+}
+
+// Usage reports the memory footprint of a subtree of a MemFS.
+type Usage struct {
+	// Bytes is the size of all file contents.
+	Bytes int64
+	// Allocated is the memory allocated for file contents.
+	// It includes the slack kept by buffers for growth and excludes holes
+	// of chunked buffers, so it may be larger or smaller than Bytes.
+	Allocated int64
+	// Files, Dirs and Symlinks count the nodes by type.
+	Files    int64
+	Dirs     int64
+	Symlinks int64
+}
+
+// Nodes returns the total number of nodes.
+func (u Usage) Nodes() int64 {
+	return u.Files + u.Dirs + u.Symlinks
+}
+
+// Usage returns the memory footprint of the subtree at path.
+// The subtree's root is counted, except for the root directory "/" of the
+// filesystem, which is not subject to the node limit.
+func (fs *MemFS) Usage(path string) (Usage, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	path = filepath.Clean(path)
+	_, fi, err := fs.fileInfo(path)
+	if err != nil {
+		return Usage{}, &os.PathError{Op: "usage", Path: path, Err: err}
+	}
+	if fi == nil {
+		return Usage{}, &os.PathError{Op: "usage", Path: path, Err: os.ErrNotExist}
+	}
+
+	var u Usage
+	fi.usage(&u)
+	if fi == fs.root {
+		u.Dirs--
+	}
+	return u, nil
+}
+
+// usage adds the footprint of the node and its children to u.
+func (fi *fileInfo) usage(u *Usage) {
+	switch {
+	case fi.dir:
+		u.Dirs++
+		for _, c := range fi.childs {
+			c.usage(u)
+		}
+		return
+	case fi.mode&os.ModeSymlink != 0:
+		u.Symlinks++
+	default:
+		u.Files++
+	}
+	fi.mutex.RLock()
+	u.Bytes += fi.length()
+	u.Allocated += fi.allocated
+	fi.mutex.RUnlock()
+}
+
+// reserve accounts delta allocated bytes for the content of the node.
+// The caller must hold fi.mutex.
+func (fi *fileInfo) reserve(delta int64) error {
+	if fi.quota != nil {
+		if err := fi.quota.reserve(delta); err != nil {
+			return err
+		}
+	}
+	fi.allocated += delta
+	return nil
+}
+
+// detach releases the nodes and memory of the subtree from the quota.
+// Open handles on removed files keep working, but are no longer accounted.
+func (fi *fileInfo) detach() {
+	for _, c := range fi.childs {
+		c.detach()
+	}
+	fi.mutex.Lock()
+	defer fi.mutex.Unlock()
+	if fi.quota != nil {
+		fi.quota.release(1, fi.allocated)
+		fi.quota = nil
+	}
+}
@@ -0,0 +1,190 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+)
+
+func TestLimitsNodes(t *testing.T) {
+	fs := Create().WithLimits(0, 2)
+
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/file", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	err := fs.Mkdir("/dir2", 0777)
+	if !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Expected ENOSPC error: %s", err)
+	}
+	if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Expected *os.PathError: %T", err)
+	}
+	if _, err := fs.OpenFile("/file", os.O_CREATE|os.O_RDWR, 0666); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("Expected ENOSPC error: %s", err)
+	}
+
+	// Opening existing files does not need a new node
+	if _, err := fs.OpenFile("/dir/file", os.O_CREATE|os.O_RDWR, 0666); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// Removing releases the nodes of the whole subtree
+	if err := fs.Remove("/dir"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.MkdirAll(fs, "/a/b", 0777); err != nil {
+		t.Errorf("Unexpected error after remove: %s", err)
+	}
+}
+
+func TestLimitsBytes(t *testing.T) {
+	fs := Create().WithLimits(1000, 0)
+
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The first growth allocates MinBufferSize
+	if _, err := f.Write(make([]byte, 100)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if u, _ := fs.Usage("/"); u.Bytes != 100 || u.Allocated != MinBufferSize {
+		t.Errorf("Invalid usage: %+v", u)
+	}
+
+	// Doubling would exceed the budget, so the buffer grows to the exact size
+	if _, err := f.Write(make([]byte, 900)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if u, _ := fs.Usage("/"); u.Bytes != 1000 || u.Allocated != 1000 {
+		t.Errorf("Invalid usage: %+v", u)
+	}
+
+	if n, err := f.Write([]byte{1}); !errors.Is(err, syscall.ENOSPC) || n != 0 {
+		t.Errorf("Expected ENOSPC error: %d %s", n, err)
+	}
+	if err := f.Truncate(1001); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Expected ENOSPC error: %s", err)
+	}
+
+	// Removing the file releases its memory
+	if err := fs.Remove("/file"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/other", make([]byte, 1000), 0666); err != nil {
+		t.Errorf("Unexpected error after remove: %s", err)
+	}
+}
+
+func TestLimitsChunked(t *testing.T) {
+	fs := Create().WithChunkedBuffers(100).WithLimits(300, 0)
+
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Holes are free
+	if err := f.Truncate(1 << 20); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.Seek(1000, io.SeekStart)
+	if _, err := f.Write([]byte{1}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.Seek(0, io.SeekStart)
+	if _, err := f.Write(bytes.Repeat([]byte{1}, 150)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// Writing into allocated chunks needs no memory, a new chunk does
+	if _, err := f.Write([]byte{1}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	f.Seek(250, io.SeekStart)
+	if _, err := f.Write([]byte{1}); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Expected ENOSPC error: %s", err)
+	}
+	if u, _ := fs.Usage("/file"); u.Allocated != 300 || u.Bytes != 1<<20 {
+		t.Errorf("Invalid usage: %+v", u)
+	}
+
+	// Truncating releases chunks
+	if err := f.Truncate(50); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if u, _ := fs.Usage("/file"); u.Allocated != 100 || u.Bytes != 50 {
+		t.Errorf("Invalid usage: %+v", u)
+	}
+}
+
+func TestUsageConcurrentWrites(t *testing.T) {
+	fs := Create().WithChunkedBuffers(16).WithLimits(1<<20, 0)
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	// The accounting reads the buffers under the lock of the writers
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			f.WriteAt([]byte{1}, int64(i*16))
+			f.Truncate(int64(i * 8))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := fs.Usage("/"); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestUsage(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"a/one.txt":   "1",
+		"a/b/two.txt": "22",
+		"a/link -> b": "",
+		"c/":          "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	u, err := fs.Usage("/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := Usage{Bytes: 3 + 1, Files: 2, Dirs: 3, Symlinks: 1}
+	u.Allocated = 0
+	if u != expected {
+		t.Errorf("Invalid usage: %+v, expected: %+v", u, expected)
+	}
+	if n := u.Nodes(); n != 6 {
+		t.Errorf("Invalid node count: %d", n)
+	}
+
+	if u, err := fs.Usage("/a/b"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if u.Dirs != 1 || u.Files != 1 || u.Bytes != 2 || u.Allocated < 2 {
+		t.Errorf("Invalid subtree usage: %+v", u)
+	}
+
+	if _, err := fs.Usage("/nonexisting"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error: %s", err)
+	}
+}
@@ -0,0 +1,383 @@
+package memfs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	filepath "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// Default permissions of fixture entries without a mode annotation.
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// fixtureEntry is a single file, directory or symlink of a fixture.
+type fixtureEntry struct {
+	name string
+	dir  bool
+	link string
+	mode os.FileMode
+	data []byte
+}
+
+// FromMap creates a MemFS populated from a map of paths to file contents.
+// Missing parent directories are created implicitly with DefaultDirMode.
+//
+// Keys use the same annotated syntax as the txtar file names of FromTxtar:
+//
+//	"dir/"                 an (empty) directory, the value is ignored
+//	"bin/run.sh mode=0755" a file with the given permissions
+//	"latest -> v1.2/"      a symlink pointing to "v1.2/", the value is ignored
+func FromMap(files map[string]string) (*MemFS, error) {
+	entries := make([]fixtureEntry, 0, len(files))
+	for spec, content := range files {
+		e, err := parseFixtureName(spec)
+		if err != nil {
+			return nil, err
+		}
+		e.data = []byte(content)
+		entries = append(entries, e)
+	}
+	return fromFixture(Create(), entries)
+}
+
+// FromTxtar creates a MemFS populated from a txtar archive, the format used
+// by golang.org/x/tools/txtar:
+//
+//	optional comment, ignored
+//	-- hello.txt --
+//	Hello World
+//	-- bin/run.sh mode=0755 --
+//	#!/bin/sh
+//	-- empty/ --
+//	-- link -> hello.txt --
+//
+// A file name ending with a slash denotes a directory, "name -> target"
+// denotes a symlink and a trailing "mode=NNNN" sets octal permissions,
+// including the setuid (4000), setgid (2000) and sticky (1000) bits.
+// Names may contain spaces, but no " -> " or trailing "key=value" words.
+// Missing parent directories are created implicitly with DefaultDirMode.
+func FromTxtar(data []byte) (*MemFS, error) {
+	entries, err := txtarEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	return fromFixture(Create(), entries)
+}
+
+// txtarEntries parses the entries of a txtar archive, see FromTxtar.
+func txtarEntries(data []byte) ([]fixtureEntry, error) {
+	_, files := parseTxtar(data)
+	entries := make([]fixtureEntry, 0, len(files))
+	for _, f := range files {
+		e, err := parseFixtureName(f.name)
+		if err != nil {
+			return nil, err
+		}
+		e.data = f.data
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ToTxtar dumps the tree below root in the txtar format understood by
+// FromTxtar, which makes it suitable for comparisons with golden files.
+// Entries are sorted by name and their paths are relative to root.
+// Directories are only listed if they are empty or have a mode other than
+// DefaultDirMode, files only carry a mode annotation if it differs from
+// DefaultFileMode.
+func (fs *MemFS) ToTxtar(root string) ([]byte, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	root = filepath.Clean(root)
+	_, fi, err := fs.fileInfo(root)
+	if err != nil {
+		return nil, &os.PathError{Op: "txtar", Path: root, Err: err}
+	}
+	if fi == nil {
+		return nil, &os.PathError{Op: "txtar", Path: root, Err: os.ErrNotExist}
+	}
+	if !fi.dir {
+		return nil, &os.PathError{Op: "txtar", Path: root, Err: vfs.ErrNotDirectory}
+	}
+
+	var files []txtarFile
+	dumpFixture(fi, "", &files)
+	return formatTxtar(nil, files), nil
+}
+
+// dumpFixture appends the txtar entries of all children of dir.
+func dumpFixture(dir *fileInfo, prefix string, files *[]txtarFile) {
+	names := make([]string, 0, len(dir.childs))
+	for name := range dir.childs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		node := dir.childs[name]
+		rel := prefix + name
+		switch {
+		case node.dir:
+			if len(node.childs) == 0 || node.mode&fixtureModeBits != DefaultDirMode {
+				*files = append(*files, txtarFile{name: rel + "/" + modeAnnotation(node.mode, DefaultDirMode)})
+			}
+			dumpFixture(node, rel+"/", files)
+		case node.mode&os.ModeSymlink != 0:
+			*files = append(*files, txtarFile{name: rel + " -> " + node.linkTarget()})
+		default:
+			*files = append(*files, txtarFile{name: rel + modeAnnotation(node.mode, DefaultFileMode), data: node.content()})
+		}
+	}
+}
+
+// fixtureModeBits are the mode bits stored in mode annotations.
+const fixtureModeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// modeAnnotation returns the " mode=NNNN" suffix if mode differs from def.
+// The special bits are written like chmod(1) does, e.g. 4755 for setuid.
+func modeAnnotation(mode, def os.FileMode) string {
+	if mode&fixtureModeBits == def {
+		return ""
+	}
+	octal := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		octal |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		octal |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		octal |= 01000
+	}
+	return fmt.Sprintf(" mode=%04o", octal)
+}
+
+// parseMode parses the octal value of a mode annotation, see modeAnnotation.
+func parseMode(value string) (os.FileMode, bool) {
+	octal, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || octal > 07777 {
+		return 0, false
+	}
+	mode := os.FileMode(octal).Perm()
+	if octal&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if octal&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if octal&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, true
+}
+
+// parseFixtureName parses an annotated fixture name like "bin/x mode=0755",
+// "dir/" or "link -> target". The "key=value" annotations are taken from
+// the end, so the name itself may contain spaces.
+func parseFixtureName(spec string) (fixtureEntry, error) {
+	var e fixtureEntry
+	rest := strings.TrimSpace(spec)
+	for {
+		i := strings.LastIndexByte(rest, ' ')
+		if i < 0 {
+			break
+		}
+		key, value, ok := strings.Cut(rest[i+1:], "=")
+		if !ok || !isAnnotationKey(key) {
+			break
+		}
+		if key != "mode" {
+			return fixtureEntry{}, fmt.Errorf("memfs: invalid annotation %q in fixture %q", rest[i+1:], spec)
+		}
+		mode, ok := parseMode(value)
+		if !ok {
+			return fixtureEntry{}, fmt.Errorf("memfs: invalid mode in fixture %q", spec)
+		}
+		e.mode = mode
+		rest = strings.TrimSpace(rest[:i])
+	}
+	if name, link, ok := strings.Cut(rest+" ", " -> "); ok {
+		rest, e.link = strings.TrimSpace(name), strings.TrimSpace(link)
+		if e.link == "" {
+			return fixtureEntry{}, fmt.Errorf("memfs: empty symlink target in fixture %q", spec)
+		}
+	}
+	if rest == "" {
+		return fixtureEntry{}, fmt.Errorf("memfs: empty fixture name")
+	}
+	e.name = rest
+
+	if strings.HasSuffix(e.name, "/") {
+		if e.link != "" {
+			return fixtureEntry{}, fmt.Errorf("memfs: directory %q can not be a symlink", spec)
+		}
+		e.dir = true
+	}
+	e.name = filepath.Clean("/" + e.name)
+	if e.name == "/" {
+		return fixtureEntry{}, fmt.Errorf("memfs: invalid fixture name %q", spec)
+	}
+	if e.mode == 0 {
+		e.mode = DefaultFileMode
+		if e.dir {
+			e.mode = DefaultDirMode
+		}
+	}
+	return e, nil
+}
+
+// isAnnotationKey reports whether key is a lower case word, which marks a
+// "key=value" annotation of a fixture name.
+func isAnnotationKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// fromFixture populates the empty MemFS fs with the given entries.
+// Directories are created first, followed by files and finally symlinks,
+// so links may point to any entry of the fixture.
+func fromFixture(fs *MemFS, entries []fixtureEntry) (*MemFS, error) {
+	rank := func(e fixtureEntry) int {
+		switch {
+		case e.dir:
+			return 0
+		case e.link != "":
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if ri, rj := rank(entries[i]), rank(entries[j]); ri != rj {
+			return ri < rj
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if seen[e.name] {
+			return nil, fmt.Errorf("memfs: duplicate fixture entry %q", e.name)
+		}
+		seen[e.name] = true
+		if err := vfs.MkdirAll(fs, filepath.Dir(e.name), DefaultDirMode); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch {
+		case e.dir:
+			err = vfs.MkdirAll(fs, e.name, e.mode)
+			if err == nil {
+				err = fs.Chmod(e.name, e.mode)
+			}
+		case e.link != "":
+			err = fs.Symlink(e.link, e.name)
+		default:
+			err = vfs.WriteFile(fs, e.name, e.data, e.mode)
+			if err == nil && e.mode&^os.ModePerm != 0 {
+				err = fs.Chmod(e.name, e.mode)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+// txtarFile is a single file of a txtar archive.
+type txtarFile struct {
+	name string
+	data []byte
+}
+
+var (
+	txtarNewlineMarker = []byte("\n-- ")
+	txtarMarker        = []byte("-- ")
+	txtarMarkerEnd     = []byte(" --")
+)
+
+// parseTxtar parses a txtar archive into its comment and files.
+//
+// This is a port of golang.org/x/tools/txtar.Parse.
+func parseTxtar(data []byte) (comment []byte, files []txtarFile) {
+	comment, name, data := findTxtarMarker(data)
+	for name != "" {
+		f := txtarFile{name: name}
+		f.data, name, data = findTxtarMarker(data)
+		files = append(files, f)
+	}
+	return comment, files
+}
+
+// findTxtarMarker finds the next file marker in data, returning the data
+// before the marker, the file name and the data after the marker.
+// If there is no next marker, it returns fixNL(data), "", nil.
+func findTxtarMarker(data []byte) (before []byte, name string, after []byte) {
+	var i int
+	for {
+		if name, after = isTxtarMarker(data[i:]); name != "" {
+			return data[:i], name, after
+		}
+		j := bytes.Index(data[i:], txtarNewlineMarker)
+		if j < 0 {
+			return fixNL(data), "", nil
+		}
+		i += j + 1 // positioned at start of new possible marker
+	}
+}
+
+// isTxtarMarker checks whether data begins with a file marker line.
+// If so, it returns the name from the line and the data after the line.
+func isTxtarMarker(data []byte) (name string, after []byte) {
+	if !bytes.HasPrefix(data, txtarMarker) {
+		return "", nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data, after = data[:i], data[i+1:]
+	}
+	if !(bytes.HasSuffix(data, txtarMarkerEnd) && len(data) >= len(txtarMarker)+len(txtarMarkerEnd)) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data[len(txtarMarker) : len(data)-len(txtarMarkerEnd)])), after
+}
+
+// fixNL returns data with a final newline added, if it is missing.
+func fixNL(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	d := make([]byte, len(data)+1)
+	copy(d, data)
+	d[len(data)] = '\n'
+	return d
+}
+
+// formatTxtar returns the serialized form of a txtar archive.
+//
+// This is a port of golang.org/x/tools/txtar.Format.
+func formatTxtar(comment []byte, files []txtarFile) []byte {
+	var buf bytes.Buffer
+	buf.Write(fixNL(comment))
+	for _, f := range files {
+		fmt.Fprintf(&buf, "-- %s --\n", f.name)
+		buf.Write(fixNL(f.data))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,180 @@
+package memfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+)
+
+const fixtureTxtar = `This comment is ignored.
+-- bin/run.sh mode=0755 --
+#!/bin/sh
+echo hello
+-- empty/ --
+-- etc/config.txt --
+key=value
+-- latest -> etc/config.txt --
+-- private/ mode=0700 --
+`
+
+func TestFromTxtar(t *testing.T) {
+	fs, err := FromTxtar([]byte(fixtureTxtar))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if b, err := vfs.ReadFile(fs, "/bin/run.sh"); err != nil {
+		t.Errorf("Unexpected error reading file: %s", err)
+	} else if s := string(b); s != "#!/bin/sh\necho hello\n" {
+		t.Errorf("Invalid content: %q", s)
+	}
+	if fi, err := fs.Stat("/bin/run.sh"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if m := fi.Mode(); m != 0755 {
+		t.Errorf("Invalid mode: %s", m)
+	}
+
+	// Implicit parent directory
+	if fi, err := fs.Stat("/etc"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if !fi.IsDir() || fi.Mode().Perm() != DefaultDirMode {
+		t.Errorf("Invalid implicit directory: %v %s", fi.IsDir(), fi.Mode())
+	}
+
+	if fi, err := fs.Stat("/private"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if m := fi.Mode().Perm(); m != 0700 {
+		t.Errorf("Invalid directory mode: %s", m)
+	}
+
+	if fis, err := fs.ReadDir("/empty"); err != nil || len(fis) != 0 {
+		t.Errorf("Expected empty directory: %v %s", fis, err)
+	}
+
+	if b, err := vfs.ReadFile(fs, "/latest"); err != nil {
+		t.Errorf("Unexpected error reading symlink: %s", err)
+	} else if s := string(b); s != "key=value\n" {
+		t.Errorf("Invalid content through symlink: %q", s)
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	fs, err := FromMap(map[string]string{
+		"a/b/c.txt":        "c",
+		"a/d.txt mode=600": "d",
+		"a/e/":             "",
+		"link -> a/b":      "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if b, err := vfs.ReadFile(fs, "/link/c.txt"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if s := string(b); s != "c" {
+		t.Errorf("Invalid content: %q", s)
+	}
+	if fi, err := fs.Stat("/a/d.txt"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if m := fi.Mode(); m != 0600 {
+		t.Errorf("Invalid mode: %s", m)
+	}
+	if fi, err := fs.Stat("/a/e"); err != nil || !fi.IsDir() {
+		t.Errorf("Expected directory /a/e: %s", err)
+	}
+}
+
+func TestFromMapErrors(t *testing.T) {
+	for _, m := range []map[string]string{
+		{"file mode=999": ""},
+		{"file mode=abc": ""},
+		{"file unknown=1": ""},
+		{"file mode=17777": ""},
+		{"link -> ": ""},
+		{"dir/ -> target": ""},
+		{"/": ""},
+		{"a": "", "/a": ""},
+	} {
+		if _, err := FromMap(m); err == nil {
+			t.Errorf("Expected error for fixture %q", m)
+		}
+	}
+}
+
+func TestToTxtarRoundTrip(t *testing.T) {
+	fs, err := FromTxtar([]byte(fixtureTxtar))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	b, err := fs.ToTxtar("/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// The dump is sorted and has no comment, the implicit /bin and /etc are omitted.
+	const expected = `-- bin/run.sh mode=0755 --
+#!/bin/sh
+echo hello
+-- empty/ --
+-- etc/config.txt --
+key=value
+-- latest -> etc/config.txt --
+-- private/ mode=0700 --
+`
+	if s := string(b); s != expected {
+		t.Errorf("Invalid txtar dump:\n%s", s)
+	}
+
+	// Dump a subtree
+	if b, err := fs.ToTxtar("/etc"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	} else if s := string(b); s != "-- config.txt --\nkey=value\n" {
+		t.Errorf("Invalid subtree dump: %q", s)
+	}
+
+	if _, err := fs.ToTxtar("/nonexisting"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error: %s", err)
+	}
+	if _, err := fs.ToTxtar("/etc/config.txt"); err == nil {
+		t.Errorf("Expected error dumping a file")
+	}
+}
+
+func TestToTxtarRoundTripSpecial(t *testing.T) {
+	const archive = `-- a b.txt --
+spaces
+-- bin/su mode=4755 --
+-- dir with spaces/ mode=0700 --
+-- link to a -> a b.txt --
+-- sgid/ mode=2775 --
+-- tmp/ mode=1777 --
+`
+	fs, err := FromTxtar([]byte(archive))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if fi, err := fs.Stat("/a b.txt"); err != nil || fi.Size() != 7 {
+		t.Errorf("Expected file with spaces: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat("/link to a"); err != nil || fi.Size() != 7 {
+		t.Errorf("Expected symlink with spaces: %v %v", fi, err)
+	}
+	for name, mode := range map[string]os.FileMode{
+		"/bin/su": 0755 | os.ModeSetuid,
+		"/sgid":   0775 | os.ModeSetgid,
+		"/tmp":    0777 | os.ModeSticky,
+	} {
+		if fi, err := fs.Stat(name); err != nil || fi.Mode()&^os.ModeDir != mode {
+			t.Errorf("Expected mode %s of %s: %v %v", mode, name, fi, err)
+		}
+	}
+
+	b, err := fs.ToTxtar("/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s := string(b); s != archive {
+		t.Errorf("Invalid txtar dump:\n%s", s)
+	}
+}
@@ -0,0 +1,82 @@
+package mountfs
+
+import (
+	"os"
+	filepath "path"
+
+	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/prefixfs"
+)
+
+// Bind mounts the directory srcPath of srcFS on mountPath.
+// srcFS may be the MountFS itself, unless srcPath leads back into the
+// mount, e.g. if it is mountPath or below it. Such binds fail with
+// syscall.EINVAL.
+//
+// The directory acts like the root of a chroot with the limits of
+// prefixfs.FS: paths can not leave it, neither by ".." nor by symbolic
+// links, which are resolved inside the directory. Absolute symbolic links
+// are translated, a link created as "/file" points to srcPath/file on
+// srcFS and vice versa.
+//
+// If there is an error, it will be of type *os.PathError.
+func (fs *MountFS) Bind(srcFS vfs.Filesystem, srcPath, mountPath string) error {
+	return fs.BindWithOptions(srcFS, srcPath, mountPath, MountOptions{})
+}
+
+// BindWithOptions mounts the directory srcPath of srcFS on mountPath like
+// Bind and applies the given options to all operations inside the mount.
+func (fs *MountFS) BindWithOptions(srcFS vfs.Filesystem, srcPath, mountPath string, opts MountOptions) error {
+	srcPath = filepath.Clean("/" + srcPath)
+	fi, err := srcFS.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return &os.PathError{Op: "bind", Path: srcPath, Err: vfs.ErrNotDirectory}
+	}
+
+	mp := MountPoint{Path: mountPath, FS: srcFS, Source: srcPath, Options: opts}
+	return fs.mount(mp, prefixfs.Create(srcFS, srcPath))
+}
+
+// isSelf reports whether other is the MountFS fs itself.
+func (fs MountFS) isSelf(other vfs.Filesystem) bool {
+	switch m := other.(type) {
+	case *MountFS:
+		return m.state == fs.state
+	case MountFS:
+		return m.state == fs.state
+	}
+	return false
+}
+
+// loops reports whether path leads into the mount on mountPath through
+// the binds of the MountFS fs itself, which would recurse endlessly.
+func (t *mountTable) loops(fs MountFS, path, mountPath string) bool {
+	seen := make(map[string]bool)
+	for {
+		_, innerPath, mp := t.findPoint(path)
+		if mp == mountPath {
+			return true
+		}
+		b, ok := t.table[mp]
+		if !ok || b.Source == "" || !fs.isSelf(b.FS) || seen[mp] {
+			return false
+		}
+		seen[mp] = true
+		path = filepath.Join(b.Source, innerPath)
+	}
+}
+
+// mapErrorPaths returns a copy of a PathError or LinkError with its paths
+// mapped by fn. Other errors are returned unchanged.
+func mapErrorPaths(err error, fn func(string) string) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.PathError{Op: e.Op, Path: fn(e.Path), Err: e.Err}
+	case *os.LinkError:
+		return &os.LinkError{Op: e.Op, Old: fn(e.Old), New: fn(e.New), Err: e.Err}
+	}
+	return err
+}
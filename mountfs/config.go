@@ -0,0 +1,147 @@
+package mountfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// ConfigError reports an invalid line of a mount table configuration.
+type ConfigError struct {
+	Line int    // line number, starting at 1
+	Text string // content of the line
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("mount table line %d: %v", e.Line, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// LoadConfig creates a MountFS from a mount table configuration, which
+// resembles /etc/fstab. Every line describes a mount by whitespace
+// separated fields:
+//
+//	<backend> <source> <mountpoint> [<options>]
+//
+// The backend is the name of a backend registered by vfs.RegisterBackend,
+// like "os", "mem" or "prefix", or "bind" to bind a directory of the MountFS
+// mounted by the previous lines, see Bind. A source of "-" is empty.
+// Backends other than "os" and "bind" are only available if the package
+// registering them is imported, e.g. "mem" requires memfs and "prefix"
+// requires prefixfs, if only for their side effects:
+//
+//	import _ "github.com/lordofscripts/vfs/memfs"
+//
+// Options are separated by commas, "ro", "rw", "noexec", "exec" and
+// "name=<name>" set the MountOptions, the others are passed to the backend.
+// Empty lines and lines starting with "#" are ignored.
+//
+// The mounts are applied in order, the first one must mount the root "/":
+//
+//	# backend  source     mountpoint  options
+//	os         /srv/www   /           ro
+//	mem        -          /tmp        chunk,name=scratch
+//	bind       /tmp/cache /var/cache
+//
+// Invalid lines are reported by a *ConfigError.
+func LoadConfig(r io.Reader) (*MountFS, error) {
+	fs := Create(vfs.Dummy(ErrNotMounted))
+	mountpoints := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		configError := func(err error) error {
+			return &ConfigError{Line: line, Text: text, Err: err}
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 || len(fields) > 4 {
+			return nil, configError(fmt.Errorf("expected 3 or 4 fields, got %d", len(fields)))
+		}
+		backend, source, mountpoint := fields[0], fields[1], fields[2]
+		if source == "-" {
+			source = ""
+		}
+		if !strings.HasPrefix(mountpoint, "/") {
+			return nil, configError(fmt.Errorf("mountpoint %q is not absolute", mountpoint))
+		}
+		mountpoint = fs.load().mountPath(mountpoint)
+		if len(mountpoints) == 0 && mountpoint != "/" {
+			return nil, configError(errors.New("the first mount must be the root /"))
+		}
+		if mountpoints[mountpoint] {
+			return nil, configError(fmt.Errorf("duplicate mountpoint %q", mountpoint))
+		}
+		mountpoints[mountpoint] = true
+
+		var opts MountOptions
+		backendOpts := make(map[string]string)
+		if len(fields) == 4 {
+			for _, opt := range strings.Split(fields[3], ",") {
+				key, value, _ := strings.Cut(opt, "=")
+				switch key {
+				case "":
+					return nil, configError(errors.New("empty option"))
+				case "ro", "rw":
+					opts.ReadOnly = key == "ro"
+				case "noexec", "exec":
+					opts.NoExec = key == "noexec"
+				case "name":
+					opts.Name = value
+				default:
+					backendOpts[key] = value
+				}
+			}
+		}
+
+		var err error
+		if backend == "bind" {
+			if len(backendOpts) > 0 {
+				return nil, configError(fmt.Errorf("bind: unsupported options %v", backendOpts))
+			}
+			err = fs.BindWithOptions(fs, source, mountpoint, opts)
+		} else {
+			err = mountBackend(fs, backend, source, mountpoint, backendOpts, opts)
+		}
+		if err != nil {
+			return nil, configError(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(mountpoints) == 0 {
+		return nil, &ConfigError{Line: line, Err: errors.New("no root mount")}
+	}
+	return fs, nil
+}
+
+// mountBackend creates a filesystem of the backend and mounts it.
+func mountBackend(fs *MountFS, backend, source, mountpoint string, backendOpts map[string]string, opts MountOptions) error {
+	factory, ok := vfs.LookupBackend(backend)
+	if !ok {
+		return fmt.Errorf("unknown backend %q", backend)
+	}
+	bfs, root, err := factory(source, backendOpts)
+	if err != nil {
+		return err
+	}
+	if root == "" || root == "/" {
+		return fs.MountWithOptions(bfs, mountpoint, opts)
+	}
+	return fs.BindWithOptions(bfs, root, mountpoint, opts)
+}
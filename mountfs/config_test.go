@@ -0,0 +1,86 @@
+package mountfs
+
+import (
+	"errors"
+	"os"
+	filepath "path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lordofscripts/vfs"
+	_ "github.com/lordofscripts/vfs/memfs"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("index"), 0644)
+	fixture := filepath.Join(dir, "fixture.txtar")
+	os.WriteFile(fixture, []byte("-- cache/entry --\ncached\n"), 0644)
+
+	fs, err := LoadConfig(strings.NewReader(`
+# backend  source  mountpoint  options
+mem        -       /
+os         ` + dir + `  /www  ro,name=www
+mem        ` + fixture + `  /var  noexec,chunk=4096
+bind       /var/cache  /cache
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if b, err := vfs.ReadFile(fs, "/www/index.html"); err != nil || string(b) != "index" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+	if err := vfs.WriteFile(fs, "/www/new", nil, 0666); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly: %v", err)
+	}
+	if b, err := vfs.ReadFile(fs, "/cache/entry"); err != nil || string(b) != "cached\n" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+
+	mps := fs.Mounts()
+	if len(mps) != 4 {
+		t.Fatalf("Unexpected mounts: %v", mps)
+	}
+	if mp := mps[3]; mp.Path != "/www" || mp.Source != dir || mp.Options != (MountOptions{ReadOnly: true, Name: "www"}) {
+		t.Errorf("Unexpected mount: %+v", mp)
+	}
+	if mp := mps[2]; mp.Path != "/var" || !mp.Options.NoExec {
+		t.Errorf("Unexpected mount: %+v", mp)
+	}
+	if mp := mps[1]; mp.Path != "/cache" || mp.Source != "/var/cache" {
+		t.Errorf("Unexpected mount: %+v", mp)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for config, line := range map[string]int{
+		"mem - /\nmem -":                       2,
+		"mem - /\nmem - /tmp x y":              2,
+		"mem - tmp":                            1,
+		"# comment\nmem - /tmp":                2,
+		"mem - /\n\nmem - /tmp\nmem - /tmp/":   4,
+		"mem - /\nunknown - /tmp":              2,
+		"mem - /\nmem - /tmp chunk=x":          2,
+		"mem - /\nmem - /tmp chunk=-5":         2,
+		"mem - /\nmem - /tmp maxbytes=-1":      2,
+		"mem - /\nos relative/dir /tmp":        2,
+		"mem - /\nmem - /tmp foo":              2,
+		"mem - /\nmem - /tmp ro,,noexec":       2,
+		"mem - /\nbind /missing /tmp":          2,
+		"mem - /\nbind / /tmp ro,foo":          2,
+		"mem /nonexisting.txtar /":             1,
+		"# only a comment":                     1,
+		"mem - /\nos - /tmp\nprefix - /prefix": 3,
+	} {
+		_, err := LoadConfig(strings.NewReader(config))
+		var cerr *ConfigError
+		if !errors.As(err, &cerr) {
+			t.Errorf("Expected *ConfigError for %q: %v", config, err)
+			continue
+		}
+		if cerr.Line != line {
+			t.Errorf("Expected error on line %d for %q: %v", line, config, err)
+		}
+	}
+}
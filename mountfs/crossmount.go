@@ -0,0 +1,153 @@
+package mountfs
+
+import (
+	"os"
+	filepath "path"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// CrossMountPolicy defines how operations crossing mount boundaries are handled.
+type CrossMountPolicy int
+
+const (
+	// CrossMountDeny fails renames and symlinks across mounts with ErrBoundary.
+	// Absolute symlink targets are stored as paths inside the mount.
+	CrossMountDeny CrossMountPolicy = iota
+
+	// CrossMountCopy renames across mounts by copying and deleting the
+	// source. Absolute symlink targets are stored as paths in the MountFS,
+	// so they may point to other mounts, and all symlinks are resolved by
+	// the MountFS. This requires the mounts to implement vfs.ReadlinkFS.
+	CrossMountCopy
+)
+
+// WithCrossMount sets the policy for renames and symlinks across mounts,
+// the default is CrossMountDeny.
+func (fs *MountFS) WithCrossMount(policy CrossMountPolicy) *MountFS {
+	fs.update(func(t *mountTable) error {
+		t.policy = policy
+		return nil
+	})
+	return fs
+}
+
+// lookup resolves the symbolic links of name if the policy demands it and
+// finds its mount. It returns the filesystem, the path inside of it and
+// the path of the mount.
+func (t *mountTable) lookup(name string, follow bool) (mount vfs.Filesystem, innerPath, mountPath string, err error) {
+	if t.policy == CrossMountCopy {
+		if name, err = vfs.ResolvePath(linkView{t.view()}, name, follow); err != nil {
+			return nil, "", "", err
+		}
+	}
+	mount, innerPath, mountPath = t.findPoint(name)
+	return mount, innerPath, mountPath, nil
+}
+
+// lstat is Lstat on a resolved path.
+func (t *mountTable) lstat(name string) (os.FileInfo, error) {
+	mount, innerPath, mountPath := t.findPoint(name)
+	fi, err := mount.Lstat(innerPath)
+	return fi, outerError(err, mountPath)
+}
+
+// readlink returns the stored destination of a symbolic link.
+func (t *mountTable) readlink(name string) (string, error) {
+	mount, innerPath, mountPath := t.findPoint(name)
+	target, err := vfs.Readlink(mount, innerPath)
+	return target, outerError(err, mountPath)
+}
+
+// linkView is a view of a table for vfs.ResolvePath,
+// whose Lstat and Readlink do not resolve symbolic links themselves.
+type linkView struct {
+	MountFS
+}
+
+func (v linkView) Lstat(name string) (os.FileInfo, error) {
+	return v.load().lstat(name)
+}
+
+func (v linkView) Readlink(name string) (string, error) {
+	return v.load().readlink(name)
+}
+
+// view returns a MountFS operating on the table.
+// Mounts of the view can not be changed.
+func (t *mountTable) view() MountFS {
+	state := &atomic.Pointer[mountTable]{}
+	state.Store(t)
+	return MountFS{state: state}
+}
+
+// lastTemp numbers temporary files of moves across mounts.
+var lastTemp atomic.Uint64
+
+// moveAcross renames oldpath to newpath on another mount by copying it
+// to a temporary file next to newpath, renaming this to newpath and
+// removing oldpath. The temporary file is removed if copying fails.
+// Mountpoints can not be moved.
+func (t *mountTable) moveAcross(oldpath, newpath string) error {
+	linkError := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	if t.isMountPoint(oldpath) || t.isMountPoint(newpath) || len(t.nestedMounts(oldpath)) > 0 {
+		return linkError(syscall.EBUSY)
+	}
+
+	fs := t.view()
+	fi, err := fs.Lstat(oldpath)
+	if err != nil {
+		return linkError(pathErrorCause(err))
+	}
+	if nfi, err := fs.Lstat(newpath); err == nil {
+		switch {
+		case fi.IsDir() && !nfi.IsDir():
+			return linkError(syscall.ENOTDIR)
+		case !fi.IsDir() && nfi.IsDir():
+			return linkError(syscall.EISDIR)
+		case nfi.IsDir():
+			if fis, err := fs.ReadDir(newpath); err != nil || len(fis) > 0 {
+				return linkError(syscall.ENOTEMPTY)
+			}
+		}
+	}
+
+	tmp := filepath.Join(filepath.Dir(newpath), "."+filepath.Base(newpath)+".tmp"+strconv.FormatUint(lastTemp.Add(1), 10))
+	if err := vfs.CopyTree(fs, oldpath, fs, tmp, vfs.CopyOptions{PreserveMode: true, PreserveTimes: true}); err != nil {
+		fs.RemoveAll(tmp)
+		return linkError(err)
+	}
+	if err := fs.Rename(tmp, newpath); err != nil {
+		fs.RemoveAll(tmp)
+		return linkError(err)
+	}
+	// Renaming may change the modification time
+	if fi.Mode()&os.ModeSymlink == 0 {
+		if err := vfs.Preserve(fs, newpath, fi); err != nil {
+			return linkError(err)
+		}
+	}
+	if err := fs.RemoveAll(oldpath); err != nil {
+		return linkError(err)
+	}
+	return nil
+}
+
+// isMountPoint reports whether a filesystem is mounted on the clean path.
+func (t *mountTable) isMountPoint(path string) bool {
+	_, ok := t.mounts[path]
+	return ok || path == "/"
+}
+
+// pathErrorCause returns the cause of a PathError.
+func pathErrorCause(err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err
+	}
+	return err
+}
@@ -1,25 +1,57 @@
 package mountfs
 
 import (
+	"context"
 	"errors"
+	"io"
 	"os"
 	filepath "path"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/prefixfs"
 )
 
-// ErrBoundary is returned if an operation
-// can not act across filesystem boundaries.
-var ErrBoundary = errors.New("Crossing boundary")
+var (
+	// ErrBoundary is returned if an operation
+	// can not act across filesystem boundaries.
+	// It matches syscall.EXDEV for errors.Is, so vfs.Move copies instead.
+	ErrBoundary error = boundaryError{}
+
+	// ErrNotMounted is returned on unmounting a path which is not a mountpoint.
+	ErrNotMounted = errors.New("Not mounted")
+
+	// ErrBusy is returned on unmounting a path which has nested mounts
+	// or is the root.
+	ErrBusy = errors.New("Mount is busy")
+)
+
+// boundaryError is the type of ErrBoundary.
+type boundaryError struct{}
+
+func (boundaryError) Error() string { return "Crossing boundary" }
+
+// Is reports whether target is syscall.EXDEV.
+func (boundaryError) Is(target error) bool { return target == syscall.EXDEV }
 
 // Create a new MountFS based on a root filesystem.
 func Create(rootFS vfs.Filesystem) *MountFS {
-	return &MountFS{
-		rootFS:  rootFS,
-		mounts:  make(map[string]vfs.Filesystem),
-		parents: make(map[string][]string),
+	fs := &MountFS{
+		mutex: &sync.Mutex{},
+		state: &atomic.Pointer[mountTable]{},
 	}
+	fs.state.Store(&mountTable{
+		rootFS: rootFS,
+		root:   MountPoint{Path: "/", FS: rootFS},
+		mounts: make(map[string]vfs.Filesystem),
+		table:  make(map[string]MountPoint),
+	})
+	return fs
 }
 
 // MountFS represents a filesystem build upon a root filesystem
@@ -28,13 +60,72 @@ func Create(rootFS vfs.Filesystem) *MountFS {
 // not be a directory or event exist.
 //
 // Only filesystems with the same path separator are compatible.
-// It's not possible to mount a specific source directory, only the
-// root of the filesystem can be mounted, use a chroot in this case.
+// Mount mounts the root of a filesystem, use Bind to mount
+// a directory of a filesystem.
 // The resulting filesystem is case-sensitive.
+//
+// MountFS is safe for concurrent use, mounts may be changed while other
+// operations are running. Every operation resolves its paths on the mount
+// table at its start, so it acts either before or after a concurrent change.
+// Unmounting is lazy: files opened before keep working on the
+// detached filesystem until they are closed.
 type MountFS struct {
-	rootFS  vfs.Filesystem
-	mounts  map[string]vfs.Filesystem
-	parents map[string][]string
+	mutex *sync.Mutex                 // serializes changes of the mount table
+	state *atomic.Pointer[mountTable] // current mount table, never modified
+}
+
+// mountTable is an immutable snapshot of the mounts.
+// Changes are applied on a copy, which replaces the current table.
+type mountTable struct {
+	policy CrossMountPolicy
+	rootFS vfs.Filesystem            // root filesystem with options applied
+	root   MountPoint                // root filesystem as mounted
+	mounts map[string]vfs.Filesystem // filesystems with options applied
+	table  map[string]MountPoint     // filesystems as mounted
+}
+
+// MountPoint describes a filesystem mounted in a MountFS.
+type MountPoint struct {
+	Path    string
+	FS      vfs.Filesystem // the filesystem as given to Mount
+	Source  string         // the directory of FS given to Bind, empty if the root is mounted
+	Options MountOptions
+}
+
+// load returns the current mount table.
+func (fs MountFS) load() *mountTable {
+	return fs.state.Load()
+}
+
+// update applies fn to a copy of the mount table and replaces the
+// current table by it, unless fn fails.
+func (fs *MountFS) update(fn func(t *mountTable) error) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	t := fs.load().clone()
+	if err := fn(t); err != nil {
+		return err
+	}
+	fs.state.Store(t)
+	return nil
+}
+
+func (t *mountTable) clone() *mountTable {
+	c := &mountTable{
+		policy: t.policy,
+		rootFS: t.rootFS,
+		root:   t.root,
+		mounts: make(map[string]vfs.Filesystem, len(t.mounts)),
+		table:  make(map[string]MountPoint, len(t.table)),
+	}
+	for p, m := range t.mounts {
+		c.mounts[p] = m
+	}
+	for p, mp := range t.table {
+		c.table[p] = mp
+	}
+	return c
 }
 
 // Mount mounts a filesystem on the given path.
@@ -43,47 +134,203 @@ type MountFS struct {
 // Path `/` can be used to change rootfs.
 // Only absolute paths are allowed.
 func (fs *MountFS) Mount(mount vfs.Filesystem, path string) error {
-	pathSeparator := string(fs.rootFS.PathSeparator())
+	return fs.MountWithOptions(mount, path, MountOptions{})
+}
+
+// MountWithOptions mounts a filesystem on the given path like Mount
+// and applies the given options to all operations inside the mount.
+func (fs *MountFS) MountWithOptions(mount vfs.Filesystem, path string, opts MountOptions) error {
+	return fs.mount(MountPoint{Path: path, FS: mount, Options: opts}, mount)
+}
+
+// mount adds the mountpoint with the filesystem used for its operations.
+func (fs *MountFS) mount(mp MountPoint, mount vfs.Filesystem) error {
+	return fs.update(func(t *mountTable) error {
+		path := t.mountPath(mp.Path)
+		mp.Path = path
+		mount = mp.Options.apply(mount)
+
+		// Change rootfs
+		if path == "/" {
+			t.root = mp
+			t.rootFS = mount
+		} else {
+			t.mounts[path] = mount
+			t.table[path] = mp
+		}
+		if mp.Source != "" && fs.isSelf(mp.FS) && t.loops(*fs, mp.Source, path) {
+			return &os.PathError{Op: "bind", Path: path, Err: syscall.EINVAL}
+		}
+		return nil
+	})
+}
+
+// Unmount detaches the filesystem mounted on the given path.
+// It fails with ErrBusy if other filesystems are mounted inside of it,
+// use ForceUnmount to detach them as well. The root can not be unmounted.
+// Open files of the filesystem keep working.
+// If there is an error, it will be of type *os.PathError.
+func (fs *MountFS) Unmount(path string) error {
+	return fs.update(func(t *mountTable) error {
+		return t.unmount(path, false)
+	})
+}
+
+// ForceUnmount detaches the filesystem mounted on the given path and all
+// filesystems mounted inside of it.
+// Open files of the filesystems keep working.
+// If there is an error, it will be of type *os.PathError.
+func (fs *MountFS) ForceUnmount(path string) error {
+	return fs.update(func(t *mountTable) error {
+		return t.unmount(path, true)
+	})
+}
+
+func (t *mountTable) unmount(path string, force bool) error {
+	path = t.mountPath(path)
+	if path == "/" {
+		return &os.PathError{Op: "unmount", Path: path, Err: ErrBusy}
+	}
+	if _, ok := t.mounts[path]; !ok {
+		return &os.PathError{Op: "unmount", Path: path, Err: ErrNotMounted}
+	}
+
+	nested := t.nestedMounts(path)
+	if len(nested) > 0 && !force {
+		return &os.PathError{Op: "unmount", Path: path, Err: ErrBusy}
+	}
+	for _, p := range append(nested, path) {
+		delete(t.mounts, p)
+		delete(t.table, p)
+	}
+	return nil
+}
+
+// nestedMounts returns the mounts inside of the directory path.
+func (t *mountTable) nestedMounts(path string) []string {
+	prefix := strings.TrimSuffix(path, t.separator()) + t.separator()
+	var nested []string
+	for p := range t.mounts {
+		if strings.HasPrefix(p, prefix) {
+			nested = append(nested, p)
+		}
+	}
+	return nested
+}
+
+// Mounts returns the mount table sorted by path, the root filesystem first.
+func (fs MountFS) Mounts() []MountPoint {
+	t := fs.load()
+	mps := make([]MountPoint, 0, len(t.table)+1)
+	for _, mp := range t.table {
+		mps = append(mps, mp)
+	}
+	sort.Slice(mps, func(i, j int) bool { return mps[i].Path < mps[j].Path })
+	return append([]MountPoint{t.root}, mps...)
+}
+
+// mountPath cleans the path and makes it absolute.
+func (t *mountTable) mountPath(path string) string {
+	pathSeparator := t.separator()
 
-	// Clean path and make absolute
 	path = filepath.Clean(path)
 	segm := vfs.SplitPath(path, pathSeparator)
 	segm[0] = "" // make absolute
 	path = strings.Join(segm, pathSeparator)
-
-	// Change rootfs disabled
 	if path == "" {
-		fs.rootFS = mount
-		return nil
+		return "/"
 	}
+	return path
+}
 
-	parent := strings.Join(segm[0:len(segm)-1], pathSeparator)
-	if parent == "" {
-		parent = "/"
-	}
-	fs.parents[parent] = append(fs.parents[parent], path)
-	fs.mounts[path] = mount
-	return nil
+func (t *mountTable) separator() string {
+	return string(t.rootFS.PathSeparator())
+}
+
+// find finds the mount of the given path in the table.
+// It returns the corresponding filesystem and the path inside of this filesystem.
+func (t *mountTable) find(path string) (vfs.Filesystem, string) {
+	return findMount(path, t.mounts, t.rootFS, t.separator())
+}
+
+// findPoint is like find, but additionally returns the path of the mount.
+func (t *mountTable) findPoint(path string) (vfs.Filesystem, string, string) {
+	return findMountPoint(path, t.mounts, t.rootFS, t.separator())
 }
 
 // PathSeparator returns the path separator
 func (fs MountFS) PathSeparator() uint8 {
-	return fs.rootFS.PathSeparator()
+	return fs.load().rootFS.PathSeparator()
+}
+
+// WithContext implements vfs.FilesystemContext. It returns a view of the
+// current mounts, which binds ctx to every mounted filesystem.
+// Mounts of the view can not be changed, later changes of the MountFS
+// do not affect it. Binds of the MountFS itself bind the view instead.
+func (fs MountFS) WithContext(ctx context.Context) vfs.Filesystem {
+	t := fs.load().clone()
+	view := t.view()
+	t.rootFS = vfs.WithContext(ctx, t.rootFS)
+	for p, m := range t.mounts {
+		mp := t.table[p]
+		if !fs.isSelf(mp.FS) {
+			t.mounts[p] = vfs.WithContext(ctx, m)
+			continue
+		}
+		// Binding ctx to the MountFS would recurse into this view
+		mp.FS = view
+		t.table[p] = mp
+		if mp.Source != "" {
+			t.mounts[p] = mp.Options.apply(prefixfs.Create(view, mp.Source))
+		} else {
+			t.mounts[p] = mp.Options.apply(view)
+		}
+	}
+	return view
 }
 
 // findMount finds a valid mountpoint for the given path.
 // It returns the corresponding filesystem and the path inside of this filesystem.
 func findMount(path string, mounts map[string]vfs.Filesystem, fallback vfs.Filesystem, pathSeparator string) (vfs.Filesystem, string) {
+	fs, innerPath, _ := findMountPoint(path, mounts, fallback, pathSeparator)
+	return fs, innerPath
+}
+
+// findMountPoint is like findMount, but additionally returns the path
+// of the mountpoint, which is "/" for the fallback.
+func findMountPoint(path string, mounts map[string]vfs.Filesystem, fallback vfs.Filesystem, pathSeparator string) (vfs.Filesystem, string, string) {
 	path = filepath.Clean(path)
 	segs := vfs.SplitPath(path, pathSeparator)
 	l := len(segs)
 	for i := l; i > 0; i-- {
 		mountPath := strings.Join(segs[0:i], pathSeparator)
 		if fs, ok := mounts[mountPath]; ok {
-			return fs, "/" + strings.Join(segs[i:l], pathSeparator)
+			return fs, "/" + strings.Join(segs[i:l], pathSeparator), mountPath
 		}
 	}
-	return fallback, path
+	return fallback, path, "/"
+}
+
+// outerPath returns the path in the MountFS of a path inside the mount on mountPath.
+func outerPath(mountPath, path string) string {
+	if mountPath == "/" || !strings.HasPrefix(path, "/") {
+		return path
+	}
+	if path == "/" {
+		return mountPath
+	}
+	return mountPath + path
+}
+
+// outerError reports the paths of PathErrors and LinkErrors of the mount
+// on mountPath as paths in the MountFS.
+func outerError(err error, mountPath string) error {
+	if mountPath == "/" {
+		return err
+	}
+	return mapErrorPaths(err, func(path string) string {
+		return outerPath(mountPath, path)
+	})
 }
 
 type innerFile struct {
@@ -96,56 +343,155 @@ func (f innerFile) Name() string {
 	return f.name
 }
 
+// ReadFrom uses the io.ReaderFrom of the wrapped file if available.
+func (f innerFile) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := f.File.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(f.File, r)
+}
+
+// WriteTo uses the io.WriterTo of the wrapped file if available.
+func (f innerFile) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := f.File.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, f.File)
+}
+
 // OpenFile find the mount of the given path and executes OpenFile
 // on the corresponding filesystem.
 // It wraps the resulting file to return the path inside mountfs on Name()
 func (fs MountFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	mount, innerPath, mountPath, err := fs.load().lookup(name, true)
+	if err != nil {
+		return nil, err
+	}
 	file, err := mount.OpenFile(innerPath, flag, perm)
-	return innerFile{File: file, name: name}, err
+	return innerFile{File: file, name: name}, outerError(err, mountPath)
 }
 
 // Remove removes a file or directory
 func (fs MountFS) Remove(name string) error {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	return mount.Remove(innerPath)
+	mount, innerPath, mountPath, err := fs.load().lookup(name, false)
+	if err != nil {
+		return err
+	}
+	return outerError(mount.Remove(innerPath), mountPath)
 }
 
 func (fs MountFS) RemoveAll(path string) error {
-	mount, innerPath := findMount(path, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	return mount.RemoveAll(innerPath)
+	mount, innerPath, mountPath, err := fs.load().lookup(path, false)
+	if err != nil {
+		return err
+	}
+	return outerError(mount.RemoveAll(innerPath), mountPath)
 }
 
 // Rename renames a file.
-// Renames across filesystems are not allowed.
+// Renames across filesystems fail with ErrBoundary,
+// unless the CrossMountCopy policy is set.
 func (fs MountFS) Rename(oldpath, newpath string) error {
-	oldMount, oldInnerPath := findMount(oldpath, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	newMount, newInnerPath := findMount(newpath, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	t := fs.load()
+	if t.policy == CrossMountCopy {
+		var err error
+		if oldpath, err = vfs.ResolvePath(linkView{t.view()}, oldpath, false); err != nil {
+			return err
+		}
+		if newpath, err = vfs.ResolvePath(linkView{t.view()}, newpath, false); err != nil {
+			return err
+		}
+	}
+	oldMount, oldInnerPath, mountPath := t.findPoint(oldpath)
+	newMount, newInnerPath := t.find(newpath)
 	if oldMount != newMount {
+		if t.policy == CrossMountCopy {
+			return t.moveAcross(oldpath, newpath)
+		}
 		return ErrBoundary
 	}
-	return oldMount.Rename(oldInnerPath, newInnerPath)
+	return outerError(oldMount.Rename(oldInnerPath, newInnerPath), mountPath)
 }
 
 // Mkdir creates a directory
 func (fs MountFS) Mkdir(name string, perm os.FileMode) error {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	return mount.Mkdir(innerPath, perm)
+	mount, innerPath, mountPath, err := fs.load().lookup(name, false)
+	if err != nil {
+		return err
+	}
+	return outerError(mount.Mkdir(innerPath, perm), mountPath)
 }
 
 func (fs MountFS) MkdirAll(path string, perm os.FileMode) error {
-	mount, innerPath := findMount(path, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	return mount.MkdirAll(innerPath, perm)
+	mount, innerPath, mountPath, err := fs.load().lookup(path, true)
+	if err != nil {
+		return err
+	}
+	return outerError(mount.MkdirAll(innerPath, perm), mountPath)
 }
 
-// Symlink creates a symlink
+// Symlink creates a symlink.
+// Absolute targets are translated to paths inside the filesystem of the
+// symlink, they may not cross filesystem boundaries.
+// With the CrossMountCopy policy, absolute targets are stored as they are
+// and may point to other mounts.
+// Relative targets are kept as they are.
 func (fs MountFS) Symlink(oldname, newname string) error {
-	oldMount, oldInnerName := findMount(oldname, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	newMount, newInnerName := findMount(newname, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	if oldMount != newMount {
-		return ErrBoundary
+	t := fs.load()
+	newMount, newInnerName, mountPath, err := t.lookup(newname, false)
+	if err != nil {
+		return err
+	}
+	if t.policy != CrossMountCopy && strings.HasPrefix(oldname, "/") {
+		var oldMount vfs.Filesystem
+		oldMount, oldname = t.find(oldname)
+		if oldMount != newMount {
+			return ErrBoundary
+		}
+	}
+	return outerError(newMount.Symlink(oldname, newInnerName), mountPath)
+}
+
+// Readlink returns the destination of the named symbolic link.
+// Absolute destinations are translated to paths in the MountFS.
+// It fails with errors.ErrUnsupported if the filesystem of the link
+// does not implement vfs.ReadlinkFS.
+func (fs MountFS) Readlink(name string) (string, error) {
+	t := fs.load()
+	mount, innerPath, mountPath, err := t.lookup(name, false)
+	if err != nil {
+		return "", err
+	}
+	target, err := vfs.Readlink(mount, innerPath)
+	if err != nil {
+		return "", outerError(err, mountPath)
+	}
+	if t.policy == CrossMountCopy {
+		return target, nil
 	}
-	return oldMount.Symlink(oldInnerName, newInnerName)
+	return outerPath(mountPath, target), nil
+}
+
+// Chmod changes the mode of the named file.
+// It fails with errors.ErrUnsupported if the filesystem of the file
+// does not implement vfs.ChmodFS.
+func (fs MountFS) Chmod(name string, mode os.FileMode) error {
+	mount, innerPath, mountPath, err := fs.load().lookup(name, true)
+	if err != nil {
+		return err
+	}
+	return outerError(vfs.Chmod(mount, innerPath, mode), mountPath)
+}
+
+// Chtimes changes the access and modification times of the named file.
+// It fails with errors.ErrUnsupported if the filesystem of the file
+// does not implement vfs.ChtimesFS.
+func (fs MountFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	mount, innerPath, mountPath, err := fs.load().lookup(name, true)
+	if err != nil {
+		return err
+	}
+	return outerError(vfs.Chtimes(mount, innerPath, atime, mtime), mountPath)
 }
 
 type innerFileInfo struct {
@@ -165,44 +511,125 @@ func (fs MountFS) Open(name string) (vfs.File, error) {
 	return fs.OpenFile(name, os.O_RDONLY, 0)
 }
 
-// Stat returns the fileinfo of a file
+// Stat returns the fileinfo of a file.
+// Mountpoints are described by the root of the mounted filesystem.
+// Missing directories leading to mountpoints are reported as directories.
 func (fs MountFS) Stat(name string) (os.FileInfo, error) {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	fi, err := mount.Stat(innerPath)
-	if innerPath == "/" {
-		return innerFileInfo{FileInfo: fi, name: filepath.Base(name)}, err
+	t := fs.load()
+	mount, innerPath, mountPath, err := t.lookup(name, true)
+	if err != nil {
+		return nil, err
 	}
-	return fi, err
+	return t.statMount(mount.Stat, name, innerPath, mountPath)
 }
 
 // Lstat returns the fileinfo of a file or link.
+// Mountpoints are described by the root of the mounted filesystem.
+// Missing directories leading to mountpoints are reported as directories.
 func (fs MountFS) Lstat(name string) (os.FileInfo, error) {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	fi, err := mount.Lstat(innerPath)
+	t := fs.load()
+	mount, innerPath, mountPath, err := t.lookup(name, false)
+	if err != nil {
+		return nil, err
+	}
+	return t.statMount(mount.Lstat, name, innerPath, mountPath)
+}
+
+// statMount calls stat on the path inside a mount.
+// The root of a mount is named like the mountpoint.
+func (t *mountTable) statMount(stat func(string) (os.FileInfo, error), name, innerPath, mountPath string) (os.FileInfo, error) {
+	fi, err := stat(innerPath)
+	if err != nil {
+		if os.IsNotExist(err) && len(t.nestedMounts(outerPath(mountPath, filepath.Clean("/"+innerPath)))) > 0 {
+			return syntheticDir{name: filepath.Base(name)}, nil
+		}
+		return nil, outerError(err, mountPath)
+	}
 	if innerPath == "/" {
-		return innerFileInfo{FileInfo: fi, name: filepath.Base(name)}, err
+		return innerFileInfo{FileInfo: fi, name: filepath.Base(name)}, nil
 	}
-	return fi, err
+	return fi, nil
 }
 
 // ReadDir reads the directory named by path and returns a list of sorted directory entries.
+// Mountpoints shadow the entries of the parent filesystem and missing
+// directories leading to mountpoints are added.
 func (fs MountFS) ReadDir(path string) ([]os.FileInfo, error) {
-	path = filepath.Clean(path)
-	mount, innerPath := findMount(path, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	t := fs.load()
+	mount, innerPath, mountPath, err := t.lookup(path, true)
+	if err != nil {
+		return nil, err
+	}
 
+	path = outerPath(mountPath, filepath.Clean("/"+innerPath))
+	entries := t.mountEntries(path)
 	fis, err := mount.ReadDir(innerPath)
 	if err != nil {
-		return fis, err
+		if len(entries) == 0 {
+			return fis, outerError(err, mountPath)
+		}
+		if _, serr := mount.Stat(innerPath); !os.IsNotExist(serr) {
+			return fis, outerError(err, mountPath)
+		}
+		fis = nil
+	}
+	if len(entries) == 0 {
+		return fis, nil
+	}
+
+	merged := make([]os.FileInfo, 0, len(fis)+len(entries))
+	for _, fi := range fis {
+		entry, ok := entries[fi.Name()]
+		if ok && !t.isMountPoint(entry) && fi.IsDir() {
+			// Existing directory leading to a mountpoint
+			delete(entries, fi.Name())
+			ok = false
+		}
+		if !ok {
+			merged = append(merged, fi)
+		}
 	}
+	for name, entry := range entries {
+		if !t.isMountPoint(entry) {
+			merged = append(merged, syntheticDir{name: name})
+			continue
+		}
+		mfi, err := t.mounts[entry].Stat("/")
+		if err == nil {
+			merged = append(merged, innerFileInfo{FileInfo: mfi, name: name})
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
 
-	// Add mountpoints
-	if childs, ok := fs.parents[path]; ok {
-		for _, c := range childs {
-			mfi, err := fs.Stat(c)
-			if err == nil {
-				fis = append(fis, mfi)
-			}
+// mountEntries returns the entries of the directory path which are
+// mountpoints or lead to mountpoints, mapped to their paths.
+func (t *mountTable) mountEntries(path string) map[string]string {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var entries map[string]string
+	for p := range t.mounts {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		name, _, _ := strings.Cut(p[len(prefix):], "/")
+		if entries == nil {
+			entries = make(map[string]string)
 		}
+		entries[name] = prefix + name
 	}
-	return fis, err
+	return entries
 }
+
+// syntheticDir describes a directory leading to a mountpoint,
+// which does not exist on the parent filesystem.
+type syntheticDir struct {
+	name string
+}
+
+func (fi syntheticDir) Name() string       { return fi.name }
+func (fi syntheticDir) Size() int64        { return 0 }
+func (fi syntheticDir) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (fi syntheticDir) ModTime() time.Time { return time.Time{} }
+func (fi syntheticDir) IsDir() bool        { return true }
+func (fi syntheticDir) Sys() any           { return nil }
@@ -1,11 +1,20 @@
 package mountfs
 
 import (
+	"context"
 	"errors"
+	"io"
 	"os"
+	filepath "path"
+	"reflect"
+	"strconv"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/lordofscripts/vfs"
+	"github.com/lordofscripts/vfs/memfs"
 )
 
 type mountTest struct {
@@ -392,3 +401,612 @@ func TestReadDirMountPoints(t *testing.T) {
 		t.Errorf("Expected mountpoint, but got: %s", fis)
 	}
 }
+
+func TestOpenFileCopy(t *testing.T) {
+	fs := Create(memfs.Create())
+	fs.Mount(memfs.Create(), "/tmp")
+	if err := vfs.WriteFile(fs, "/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	src, err := fs.Open("/file")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	dst, err := fs.OpenFile("/tmp/file", os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := src.(io.WriterTo); !ok {
+		t.Errorf("File does not implement io.WriterTo")
+	}
+	if _, ok := dst.(io.ReaderFrom); !ok {
+		t.Errorf("File does not implement io.ReaderFrom")
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/tmp/file"); string(b) != "content" {
+		t.Errorf("Invalid content: %q", b)
+	}
+}
+
+func TestSameFile(t *testing.T) {
+	mfs := memfs.Create()
+	fs := Create(memfs.Create())
+	fs.Mount(mfs, "/a")
+	fs.Mount(mfs, "/b")
+	if err := vfs.WriteFile(fs, "/a/file", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/file", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The same filesystem mounted twice
+	if same, err := vfs.SameFile(fs, "/a/file", "/b/file"); err != nil || !same {
+		t.Errorf("Expected same file: %v %v", same, err)
+	}
+	// Files on different filesystems
+	if same, err := vfs.SameFile(fs, "/a/file", "/file"); err != nil || same {
+		t.Errorf("Expected different files: %v %v", same, err)
+	}
+}
+
+func TestUnmount(t *testing.T) {
+	fs := Create(memfs.Create())
+	for _, path := range []string{"/a", "/a/b", "/a/b/c", "/d"} {
+		fs.Mount(memfs.Create(), path)
+	}
+	if err := vfs.WriteFile(fs, "/a/b/file", nil, 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if err := fs.Unmount("/a/b"); !errors.Is(err, ErrBusy) {
+		t.Errorf("Expected ErrBusy: %v", err)
+	}
+	if err := fs.Unmount("/"); !errors.Is(err, ErrBusy) {
+		t.Errorf("Expected ErrBusy: %v", err)
+	}
+	if err := fs.Unmount("/nonexisting"); !errors.Is(err, ErrNotMounted) {
+		t.Errorf("Expected ErrNotMounted: %v", err)
+	}
+	if _, ok := fs.Unmount("/x").(*os.PathError); !ok {
+		t.Errorf("Expected *os.PathError")
+	}
+
+	if err := fs.Unmount("/d"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := fs.Stat("/a/b/file"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := fs.ForceUnmount("/a/b/"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := fs.Stat("/a/b/file"); !os.IsNotExist(err) {
+		t.Errorf("Expected file of unmounted fs to be gone: %v", err)
+	}
+	if err := fs.Unmount("a"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if len(fs.load().mounts) != 0 || len(fs.load().table) != 0 {
+		t.Errorf("Mount table not empty: %v", fs.load().table)
+	}
+	if fis, _ := fs.ReadDir("/"); len(fis) != 0 {
+		t.Errorf("Expected no mountpoints: %v", fis)
+	}
+}
+
+func TestMounts(t *testing.T) {
+	rootFS, tmpFS, homeFS := memfs.Create(), memfs.Create(), memfs.Create()
+	fs := Create(rootFS)
+	fs.MountWithOptions(tmpFS, "/tmp", MountOptions{Name: "tmpfs"})
+	fs.Mount(homeFS, "/home")
+	fs.Mount(homeFS, "/home")
+
+	expected := []MountPoint{
+		{Path: "/", FS: rootFS},
+		{Path: "/home", FS: homeFS},
+		{Path: "/tmp", FS: tmpFS, Options: MountOptions{Name: "tmpfs"}},
+	}
+	if mps := fs.Mounts(); !reflect.DeepEqual(mps, expected) {
+		t.Errorf("Unexpected mounts: %v", mps)
+	}
+	// Mounting the same path again replaces the mount
+	if fis, _ := fs.ReadDir("/"); len(fis) != 2 {
+		t.Errorf("Duplicate mountpoints: %v", fis)
+	}
+}
+
+func TestMountOptions(t *testing.T) {
+	mfs := memfs.Create()
+	vfs.WriteFile(mfs, "/script", nil, 0755)
+	mfs.Mkdir("/dir", 0755)
+
+	fs := Create(memfs.Create())
+	fs.MountWithOptions(mfs, "/ro", MountOptions{ReadOnly: true, NoExec: true})
+
+	if err := vfs.WriteFile(fs, "/ro/file", nil, 0666); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly: %v", err)
+	}
+	if err := fs.Mkdir("/ro/dir2", 0777); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly: %v", err)
+	}
+	if fi, err := fs.Stat("/ro/script"); err != nil || fi.Mode() != 0644 {
+		t.Errorf("Expected file without execute permissions: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat("/ro/dir"); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("Expected directory with execute permissions: %v %v", fi, err)
+	}
+	if fis, err := fs.ReadDir("/ro"); err != nil || len(fis) != 2 || fis[1].Mode() != 0644 {
+		t.Errorf("Expected file without execute permissions: %v %v", fis, err)
+	}
+
+	// The options apply to the root as well
+	fs.MountWithOptions(mfs, "/", MountOptions{ReadOnly: true})
+	if err := fs.Remove("/script"); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly: %v", err)
+	}
+	if mps := fs.Mounts(); !mps[0].Options.ReadOnly || mps[0].FS != mfs {
+		t.Errorf("Unexpected root mount: %v", mps[0])
+	}
+}
+
+func TestUnmountOpenFile(t *testing.T) {
+	mfs := memfs.Create()
+	vfs.WriteFile(mfs, "/file", []byte("content"), 0666)
+	fs := Create(memfs.Create())
+	fs.Mount(mfs, "/mnt")
+
+	f, err := fs.OpenFile("/mnt/file", os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Unmount("/mnt"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The handle keeps working on the detached filesystem
+	if _, err := f.Write([]byte("CONTENT")); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if f.Name() != "/mnt/file" {
+		t.Errorf("Unexpected name: %s", f.Name())
+	}
+	if b, _ := vfs.ReadFile(mfs, "/file"); string(b) != "CONTENT" {
+		t.Errorf("Invalid content: %q", b)
+	}
+	if _, err := fs.Stat("/mnt/file"); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be gone: %v", err)
+	}
+}
+
+func TestConcurrentMount(t *testing.T) {
+	fs := Create(memfs.Create())
+	mfs := memfs.Create()
+	vfs.WriteFile(mfs, "/file", []byte("content"), 0666)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		path := "/mnt" + strconv.Itoa(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fs.Mount(mfs, path)
+				fs.Mount(mfs, path+"/nested")
+				fs.ForceUnmount(path)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// Either the mount or the root is used, never a mix
+				if b, err := vfs.ReadFile(fs, path+"/file"); err == nil && string(b) != "content" {
+					t.Errorf("Invalid content: %q", b)
+				}
+				fs.ReadDir("/")
+				fs.Mounts()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if mps := fs.Mounts(); len(mps) != 1 {
+		t.Errorf("Expected only the root mount: %v", mps)
+	}
+}
+
+func TestBind(t *testing.T) {
+	src, err := memfs.FromMap(map[string]string{
+		"data/file":              "content",
+		"data/dir/":              "",
+		"data/abs -> /data/file": "",
+		"data/up -> ../..":       "",
+		"data/out -> /secret":    "",
+		"data/loop1 -> loop2":    "",
+		"data/loop2 -> loop1":    "",
+		"secret":                 "secret",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fs := Create(memfs.Create())
+	if err := fs.Bind(src, "/data", "/mnt"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if b, err := vfs.ReadFile(fs, "/mnt/file"); err != nil || string(b) != "content" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+	// Absolute links are translated in both directions
+	if b, err := vfs.ReadFile(fs, "/mnt/abs"); err != nil || string(b) != "content" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+	if target, err := fs.Readlink("/mnt/abs"); err != nil || target != "/mnt/file" {
+		t.Errorf("Unexpected target: %q %v", target, err)
+	}
+	if err := fs.Symlink("/mnt/dir", "/mnt/dirlink"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if target, _ := src.Readlink("/data/dirlink"); target != "/data/dir" {
+		t.Errorf("Unexpected target on source: %q", target)
+	}
+	if err := vfs.WriteFile(fs, "/mnt/dirlink/new", []byte("new"), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(src, "/data/dir/new"); string(b) != "new" {
+		t.Errorf("Unexpected content on source: %q", b)
+	}
+
+	// Links can not leave the bind
+	for _, name := range []string{"/mnt/up/secret", "/mnt/up/data/file", "/mnt/out"} {
+		if _, err := fs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be confined: %v", name, err)
+		}
+	}
+	if _, err := fs.Stat("/mnt/up/file"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := fs.Stat("/mnt/loop1"); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("Expected ELOOP: %v", err)
+	}
+
+	// Errors report the path in the MountFS
+	var pe *os.PathError
+	if _, err := fs.Stat("/mnt/dir/missing"); !errors.As(err, &pe) || pe.Path != "/mnt/dir/missing" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := fs.Bind(src, "/data/file", "/file"); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory: %v", err)
+	}
+	if mps := fs.Mounts(); len(mps) != 2 || mps[1].FS != src || mps[1].Source != "/data" {
+		t.Errorf("Unexpected mounts: %v", mps)
+	}
+}
+
+func TestBindSelf(t *testing.T) {
+	fs := Create(memfs.Create())
+	fs.Mount(memfs.Create(), "/mnt")
+	if err := fs.MkdirAll("/mnt/a/b", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Bind(fs, "/mnt/a", "/bind"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/bind/b/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/mnt/a/b/file"); string(b) != "content" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+	if fis, err := fs.ReadDir("/bind"); err != nil || len(fis) != 1 || fis[0].Name() != "b" {
+		t.Errorf("Unexpected entries: %v %v", fis, err)
+	}
+	if err := fs.RemoveAll("/bind"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := fs.Stat("/mnt/a"); err != nil {
+		t.Errorf("Expected bound directory to be kept: %v", err)
+	}
+}
+
+func TestBindSelfContext(t *testing.T) {
+	fs := Create(memfs.Create())
+	if err := fs.MkdirAll("/a/b", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Bind(fs, "/a", "/c"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	view := vfs.WithContext(ctx, fs)
+	if fi, err := view.Stat("/c/b"); err != nil || !fi.IsDir() {
+		t.Errorf("Stat = %v, %v", fi, err)
+	}
+	if fi, err := vfs.WithContext(ctx, view).Stat("/c/b"); err != nil || !fi.IsDir() {
+		t.Errorf("Stat = %v, %v", fi, err)
+	}
+	cancel()
+	if _, err := view.Stat("/c/b"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBindSelfLoop(t *testing.T) {
+	fs := Create(memfs.Create())
+	if err := fs.MkdirAll("/a/b", 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, bind := range [][2]string{{"/a", "/a"}, {"/a/b", "/a"}, {"/a", "/"}} {
+		if err := fs.Bind(fs, bind[0], bind[1]); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("Bind(%s, %s): Expected invalid argument error, got %v", bind[0], bind[1], err)
+		}
+	}
+	if _, err := fs.Stat("/a/b"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// Loops through other binds
+	if err := fs.Bind(fs, "/a", "/c"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Bind(fs, "/c", "/a"); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Expected invalid argument error, got %v", err)
+	}
+	if len(fs.Mounts()) != 2 {
+		t.Errorf("Unexpected mounts: %v", fs.Mounts())
+	}
+}
+
+func TestCrossMountRename(t *testing.T) {
+	scratch, err := memfs.FromMap(map[string]string{
+		"file":                  "content",
+		"tree/a mode=0640":      "a",
+		"tree/sub/b mode=0755":  "b",
+		"tree/link -> sub/b":    "",
+		"tree/readonly/":        "",
+		"tree/readonly/c":       "c",
+		"full/x":                "",
+		"other/":                "",
+		"other/existing":        "old",
+		"other/nonempty/":       "",
+		"other/nonempty/keep":   "",
+		"other/emptydir/":       "",
+		"other/emptydir2/":      "",
+		"other/emptydir2/inner": "",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	scratch.Chmod("/tree/readonly", 0555)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	scratch.Chtimes("/tree/sub/b", mtime, mtime)
+	scratch.Chtimes("/tree/sub", mtime, mtime)
+
+	fs := Create(memfs.Create())
+	fs.Mount(scratch, "/scratch")
+	fs.Mount(memfs.Create(), "/data")
+
+	if err := fs.Rename("/scratch/file", "/data/file"); err != ErrBoundary {
+		t.Errorf("Expected ErrBoundary without policy: %v", err)
+	}
+	fs.WithCrossMount(CrossMountCopy)
+
+	if err := fs.Rename("/scratch/file", "/data/file"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/data/file"); string(b) != "content" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+	if _, err := fs.Stat("/scratch/file"); !os.IsNotExist(err) {
+		t.Errorf("Expected source to be removed: %v", err)
+	}
+
+	// Directory trees keep modes, modification times and symlinks
+	if err := fs.Rename("/scratch/tree", "/data/tree"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for name, mode := range map[string]os.FileMode{"/data/tree/a": 0640, "/data/tree/sub/b": 0755, "/data/tree/readonly": 0555} {
+		if fi, err := fs.Stat(name); err != nil || fi.Mode().Perm() != mode {
+			t.Errorf("Unexpected mode of %s: %v %v", name, fi, err)
+		}
+	}
+	for _, name := range []string{"/data/tree/sub/b", "/data/tree/sub"} {
+		if fi, err := fs.Stat(name); err != nil || !fi.ModTime().Equal(mtime) {
+			t.Errorf("Unexpected modification time of %s: %v %v", name, fi, err)
+		}
+	}
+	if b, _ := vfs.ReadFile(fs, "/data/tree/link"); string(b) != "b" {
+		t.Errorf("Unexpected content through link: %q", b)
+	}
+	if fis, _ := fs.ReadDir("/data"); len(fis) != 2 {
+		t.Errorf("Unexpected entries: %v", fis)
+	}
+
+	// Targets are replaced like by a rename on a single filesystem
+	vfs.WriteFile(fs, "/data/new", []byte("new"), 0666)
+	if err := fs.Rename("/data/new", "/scratch/other/existing"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/scratch/other/existing"); string(b) != "new" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+	if err := fs.Rename("/data/tree/sub", "/scratch/other/emptydir"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	for target, errno := range map[string]syscall.Errno{
+		"/scratch/other/nonempty": syscall.ENOTEMPTY,
+		"/scratch/other/existing": syscall.ENOTDIR,
+		"/scratch":                syscall.EBUSY,
+	} {
+		if err := fs.Rename("/data/tree", target); !errors.Is(err, errno) {
+			t.Errorf("Expected %v for %s: %v", errno, target, err)
+		}
+	}
+	if err := fs.Rename("/data/tree/a", "/scratch/other/emptydir2"); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("Expected EISDIR: %v", err)
+	}
+	if _, ok := fs.Rename("/data", "/scratch/data").(*os.LinkError); !ok {
+		t.Errorf("Expected *os.LinkError")
+	}
+}
+
+func TestCrossMountRenameCleanup(t *testing.T) {
+	src, _ := memfs.FromMap(map[string]string{
+		"tree/a": "a",
+		"tree/b": "b",
+		"tree/c": "c",
+	})
+	fs := Create(memfs.Create()).WithCrossMount(CrossMountCopy)
+	fs.Mount(src, "/src")
+	fs.Mount(memfs.Create().WithLimits(0, 2), "/full")
+
+	if err := fs.Rename("/src/tree", "/full/tree"); !errors.Is(err, memfs.ErrNoSpace) {
+		t.Errorf("Expected ErrNoSpace: %v", err)
+	}
+	if fis, err := fs.ReadDir("/full"); err != nil || len(fis) != 0 {
+		t.Errorf("Expected partial copy to be removed: %v %v", fis, err)
+	}
+	if fis, err := fs.ReadDir("/src/tree"); err != nil || len(fis) != 3 {
+		t.Errorf("Expected source to be kept: %v %v", fis, err)
+	}
+}
+
+func TestCrossMountSymlink(t *testing.T) {
+	data, _ := memfs.FromMap(map[string]string{
+		"dir/file": "content",
+	})
+	fs := Create(memfs.Create())
+	fs.Mount(data, "/data")
+	fs.Mount(memfs.Create(), "/home")
+
+	if err := fs.Symlink("/data/dir", "/home/link"); err != ErrBoundary {
+		t.Errorf("Expected ErrBoundary without policy: %v", err)
+	}
+	fs.WithCrossMount(CrossMountCopy)
+
+	if err := fs.Symlink("/data/dir", "/home/link"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fs.Symlink("../data/dir/file", "/home/rel"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if target, err := fs.Readlink("/home/link"); err != nil || target != "/data/dir" {
+		t.Errorf("Unexpected target: %q %v", target, err)
+	}
+	for _, name := range []string{"/home/link/file", "/home/rel"} {
+		if b, err := vfs.ReadFile(fs, name); err != nil || string(b) != "content" {
+			t.Errorf("Unexpected content of %s: %q %v", name, b, err)
+		}
+	}
+	if fis, err := fs.ReadDir("/home/link"); err != nil || len(fis) != 1 {
+		t.Errorf("Unexpected entries: %v %v", fis, err)
+	}
+	if fi, err := fs.Lstat("/home/link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected symlink: %v %v", fi, err)
+	}
+	if err := vfs.WriteFile(fs, "/home/link/new", nil, 0666); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := data.Stat("/dir/new"); err != nil {
+		t.Errorf("Expected file on the target mount: %v", err)
+	}
+}
+
+func TestSyntheticDirs(t *testing.T) {
+	fs := Create(memfs.Create())
+	fs.Mount(memfs.Create(), "/a/b/c")
+
+	for _, name := range []string{"/a", "/a/b"} {
+		fi, err := fs.Stat(name)
+		if err != nil || !fi.IsDir() || fi.Name() != filepath.Base(name) {
+			t.Errorf("Expected directory %s: %v %v", name, fi, err)
+		}
+		if fi, err := fs.Lstat(name); err != nil || !fi.IsDir() {
+			t.Errorf("Expected directory %s: %v %v", name, fi, err)
+		}
+	}
+	if _, err := fs.Stat("/a/x"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist: %v", err)
+	}
+
+	for dir, name := range map[string]string{"/": "a", "/a": "b", "/a/b": "c"} {
+		fis, err := fs.ReadDir(dir)
+		if err != nil || len(fis) != 1 || fis[0].Name() != name || !fis[0].IsDir() {
+			t.Errorf("Unexpected entries of %s: %v %v", dir, fis, err)
+		}
+	}
+	if _, err := fs.ReadDir("/x"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist: %v", err)
+	}
+}
+
+func TestMountShadowing(t *testing.T) {
+	rootFS, _ := memfs.FromMap(map[string]string{
+		"tmp/hidden": "",
+		"home/":      "",
+		"var/":       "",
+		"zzz":        "",
+	})
+	tmpFS, _ := memfs.FromMap(map[string]string{
+		"visible": "",
+	})
+	tmpFS.Chmod("/", 01777)
+	fs := Create(rootFS)
+	fs.Mount(tmpFS, "/tmp")
+	fs.Mount(memfs.Create(), "/var/lib/data")
+
+	fis, err := fs.ReadDir("/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"home", "tmp", "var", "zzz"}) {
+		t.Errorf("Unexpected entries: %v", names)
+	}
+	// The mountpoint is described by the root of the mount
+	root, _ := tmpFS.Stat("/")
+	if fi := fis[1]; fi.Mode() != root.Mode() || !fi.ModTime().Equal(root.ModTime()) {
+		t.Errorf("Unexpected mountpoint: %v %v", fi.Mode(), fi.ModTime())
+	}
+	if fi, err := fs.Stat("/tmp"); err != nil || fi.Mode() != root.Mode() {
+		t.Errorf("Unexpected mountpoint: %v %v", fi, err)
+	}
+	// Existing directories leading to a mountpoint are kept
+	if fi := fis[2]; fi.Sys() == nil {
+		t.Errorf("Expected directory of the root filesystem: %v", fi)
+	}
+}
+
+func TestWalkMounts(t *testing.T) {
+	fs := Create(memfs.Create())
+	fs.Mkdir("/dir", 0777)
+	mfs, _ := memfs.FromMap(map[string]string{
+		"sub/file": "",
+	})
+	fs.Mount(mfs, "/mnt/data")
+	fs.Mount(memfs.Create(), "/dir")
+
+	var visited []string
+	err := vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, filepath.Clean(path))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []string{"/", "/dir", "/mnt", "/mnt/data", "/mnt/data/sub", "/mnt/data/sub/file"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Unexpected paths: %v", visited)
+	}
+}
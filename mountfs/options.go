@@ -0,0 +1,99 @@
+package mountfs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/lordofscripts/vfs"
+)
+
+// MountOptions are applied to all operations inside a mount.
+type MountOptions struct {
+	// ReadOnly disables all write operations, see vfs.ReadOnly.
+	ReadOnly bool
+
+	// NoExec reports regular files as not executable and strips the
+	// execute permissions from new files.
+	NoExec bool
+
+	// Name is a display name of the mount, e.g. the source of the filesystem.
+	Name string
+}
+
+// apply wraps the filesystem according to the options.
+func (o MountOptions) apply(fs vfs.Filesystem) vfs.Filesystem {
+	if o.NoExec {
+		fs = noExecFS{Filesystem: fs}
+	}
+	if o.ReadOnly {
+		fs = vfs.ReadOnly(fs)
+	}
+	return fs
+}
+
+const execBits os.FileMode = 0111
+
+// noExecFS hides the execute permissions of regular files.
+type noExecFS struct {
+	vfs.Filesystem
+}
+
+// WithContext binds ctx to the wrapped filesystem.
+func (fs noExecFS) WithContext(ctx context.Context) vfs.Filesystem {
+	return noExecFS{Filesystem: vfs.WithContext(ctx, fs.Filesystem)}
+}
+
+// OpenFile strips the execute permissions from perm.
+func (fs noExecFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	return fs.Filesystem.OpenFile(name, flag, perm&^execBits)
+}
+
+// Stat reports regular files as not executable.
+func (fs noExecFS) Stat(name string) (os.FileInfo, error) {
+	return noExec(fs.Filesystem.Stat(name))
+}
+
+// Lstat reports regular files as not executable.
+func (fs noExecFS) Lstat(name string) (os.FileInfo, error) {
+	return noExec(fs.Filesystem.Lstat(name))
+}
+
+// Chmod strips the execute permissions from mode.
+func (fs noExecFS) Chmod(name string, mode os.FileMode) error {
+	return vfs.Chmod(fs.Filesystem, name, mode&^execBits)
+}
+
+// Chtimes changes the access and modification times of a file.
+func (fs noExecFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return vfs.Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// Readlink returns the destination of a symbolic link.
+func (fs noExecFS) Readlink(name string) (string, error) {
+	return vfs.Readlink(fs.Filesystem, name)
+}
+
+// ReadDir reports regular files as not executable.
+func (fs noExecFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	for i, fi := range fis {
+		fis[i], _ = noExec(fi, nil)
+	}
+	return fis, err
+}
+
+func noExec(fi os.FileInfo, err error) (os.FileInfo, error) {
+	if err != nil || fi.IsDir() || !fi.Mode().IsRegular() {
+		return fi, err
+	}
+	return noExecFileInfo{FileInfo: fi}, nil
+}
+
+type noExecFileInfo struct {
+	os.FileInfo
+}
+
+func (fi noExecFileInfo) Mode() os.FileMode {
+	return fi.FileInfo.Mode() &^ execBits
+}
@@ -3,6 +3,7 @@ package vfs
 import (
 	"io/ioutil"
 	"os"
+	"time"
 )
 
 // OsFS represents a filesystem backed by the filesystem of the underlying OS.
@@ -56,6 +57,21 @@ func (fs OsFS) Symlink(oldname, newname string) error {
 	return os.Symlink(oldname, newname)
 }
 
+// Chmod wraps os.Chmod
+func (fs OsFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Chtimes wraps os.Chtimes
+func (fs OsFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// Readlink wraps os.Readlink
+func (fs OsFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
 // Rename wraps os.Rename
 func (fs OsFS) Rename(oldpath, newpath string) error {
 	return os.Rename(oldpath, newpath)
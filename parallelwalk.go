@@ -0,0 +1,349 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// ParallelWalkOptions configure ParallelWalk.
+type ParallelWalkOptions struct {
+	WalkOptions
+
+	// Workers is the maximum number of directories read concurrently,
+	// runtime.GOMAXPROCS(0) if it is not positive.
+	Workers int
+
+	// Ordered calls the walk func sequentially in the order of WalkDir,
+	// which makes the output deterministic. The directories are still
+	// read concurrently ahead of the callbacks, up to Workers
+	// subdirectories of every directory being walked.
+	Ordered bool
+
+	// ContinueOnError keeps walking if the walk func returns an error.
+	// All errors are returned when the walk is done.
+	ContinueOnError bool
+}
+
+func (o ParallelWalkOptions) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// ParallelWalk walks the file tree rooted at root like WalkDir, but reads
+// the directories concurrently by a pool of workers. Unless opts.Ordered
+// is set, the entries are visited in no particular order and fn is called
+// concurrently by the workers, so it must be safe for concurrent use.
+//
+// fn may return fs.SkipDir and fs.SkipAll like for WalkDir. Another error
+// stops the walk, unless opts.ContinueOnError is set. The walk stops once
+// ctx is done. All errors returned by fn and the error of ctx are joined
+// by errors.Join.
+func ParallelWalk(ctx context.Context, fs Filesystem, root string, fn iofs.WalkDirFunc, opts ParallelWalkOptions) error {
+	c, info, err := newWalkConfig(fs, root, opts.WalkOptions)
+	if err != nil {
+		err = fn(root, nil, err)
+		if err == iofs.SkipDir || err == iofs.SkipAll {
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &parallelWalker{ctx: ctx, cancel: cancel, c: c, fs: fs, fn: fn, opts: opts}
+	t := c.prepare(root, info, nil)
+	if opts.Ordered {
+		w.sem = make(chan struct{}, opts.workers())
+		w.walkOrdered(root, t, nil, nil)
+		w.wg.Wait()
+	} else {
+		w.walkUnordered(root, t)
+	}
+	return errors.Join(w.errs...)
+}
+
+// WalkEntry is an entry of a file tree delivered by ParallelWalkEntries.
+type WalkEntry struct {
+	Path  string
+	Entry iofs.DirEntry // nil if the root can not be read
+	Err   error         // error reading the entry or directory
+}
+
+// ParallelWalkEntries walks the file tree rooted at root like ParallelWalk
+// and delivers the entries on the returned channel, which is closed when
+// the walk is done. Errors of Lstat and ReadDir are delivered in the entries,
+// a directory which can not be read is delivered twice like for WalkDir.
+// The channel must be drained, or ctx must be cancelled to stop the walk.
+func ParallelWalkEntries(ctx context.Context, fs Filesystem, root string, opts ParallelWalkOptions) <-chan WalkEntry {
+	ch := make(chan WalkEntry, opts.workers())
+	go func() {
+		defer close(ch)
+		ParallelWalk(ctx, fs, root, func(path string, d iofs.DirEntry, err error) error {
+			select {
+			case ch <- WalkEntry{Path: path, Entry: d, Err: err}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts)
+	}()
+	return ch
+}
+
+// visitResult tells a walker how to continue after visiting an entry.
+type visitResult int
+
+const (
+	visitContinue visitResult = iota // descend into directories
+	visitSkip                        // skip the directory or the remaining entries
+	visitStop                        // stop walking
+)
+
+// parallelWalker holds the state of a ParallelWalk.
+type parallelWalker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	c      *walkConfig
+	fs     Filesystem
+	fn     iofs.WalkDirFunc
+	opts   ParallelWalkOptions
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []dirJob // directories to read
+	pending int      // queued and running jobs
+	errs    []error
+	stopped bool
+
+	sem chan struct{}  // limits concurrent reads of the ordered walk
+	wg  sync.WaitGroup // running reads of the ordered walk
+}
+
+// dirJob is a directory to read by the unordered walk.
+type dirJob struct {
+	path      string
+	d         iofs.DirEntry
+	ancestors []fileID // of the entries, see walkConfig.prepare
+}
+
+// stop stops the walk and records err if it is not nil.
+func (w *parallelWalker) stop(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil && (!w.stopped || err != w.ctx.Err()) {
+		w.errs = append(w.errs, err)
+	}
+	w.stopped = true
+	w.cancel()
+	if w.cond != nil {
+		w.cond.Broadcast()
+	}
+}
+
+func (w *parallelWalker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+// visit calls fn for an entry and decides how to continue.
+func (w *parallelWalker) visit(path string, d iofs.DirEntry, err error) visitResult {
+	if w.isStopped() {
+		return visitStop
+	}
+	if ctxErr := w.ctx.Err(); ctxErr != nil {
+		w.stop(ctxErr)
+		return visitStop
+	}
+	switch err := w.fn(path, d, err); {
+	case err == nil:
+		return visitContinue
+	case err == iofs.SkipDir:
+		return visitSkip
+	case err == iofs.SkipAll:
+		w.stop(nil)
+		return visitStop
+	case w.opts.ContinueOnError:
+		w.mu.Lock()
+		w.errs = append(w.errs, err)
+		w.mu.Unlock()
+		return visitContinue
+	default:
+		w.stop(err)
+		return visitStop
+	}
+}
+
+// walkUnordered visits the root and reads the directories by the workers.
+func (w *parallelWalker) walkUnordered(root string, t walkTarget) {
+	if w.visit(root, t.d, t.err) != visitContinue || !t.descend {
+		return
+	}
+	w.cond = sync.NewCond(&w.mu)
+	w.queue = []dirJob{{path: root, d: t.d, ancestors: w.c.descendants(nil, t)}}
+	w.pending = 1
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, ok := w.next()
+				if !ok {
+					return
+				}
+				w.readDir(job)
+				w.done()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// next returns the next directory to read. It waits until one is queued
+// and reports false once all directories are read or the walk is stopped.
+func (w *parallelWalker) next() (dirJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 && !w.stopped {
+		w.cond.Wait()
+	}
+	if w.stopped || len(w.queue) == 0 {
+		return dirJob{}, false
+	}
+	job := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return job, true
+}
+
+func (w *parallelWalker) push(job dirJob) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.queue = append(w.queue, job)
+	w.pending++
+	w.cond.Signal()
+}
+
+func (w *parallelWalker) done() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending--; w.pending == 0 {
+		w.cond.Broadcast()
+	}
+}
+
+// readDir reads a directory of the unordered walk, visits its entries
+// and queues its subdirectories.
+func (w *parallelWalker) readDir(job dirJob) {
+	if err := w.ctx.Err(); err != nil {
+		w.stop(err)
+		return
+	}
+	infos, err := readDir(w.fs, job.path, true)
+	if err != nil {
+		w.visit(job.path, job.d, err)
+		return
+	}
+	for _, info := range infos {
+		path := joinPath(w.fs, job.path, info.Name())
+		t := w.c.prepare(path, info, job.ancestors)
+		switch w.visit(path, t.d, t.err) {
+		case visitSkip:
+			if !t.d.IsDir() {
+				return
+			}
+			continue
+		case visitStop:
+			return
+		}
+		if t.descend {
+			w.push(dirJob{path: path, d: t.d, ancestors: w.c.descendants(job.ancestors, t)})
+		}
+	}
+}
+
+// listing is the result of a directory read ahead by the ordered walk.
+type listing struct {
+	done  chan struct{}
+	infos []os.FileInfo
+	err   error
+}
+
+// prefetch reads the directory by a worker.
+func (w *parallelWalker) prefetch(path string) *listing {
+	l := &listing{done: make(chan struct{})}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(l.done)
+		select {
+		case w.sem <- struct{}{}:
+		case <-w.ctx.Done():
+			l.err = w.ctx.Err()
+			return
+		}
+		defer func() { <-w.sem }()
+		l.infos, l.err = readDir(w.fs, path, w.opts.Unsorted)
+	}()
+	return l
+}
+
+// walkOrdered recursively descends path like walkDir. The next subdirectories
+// of a directory are read ahead while its entries are visited.
+func (w *parallelWalker) walkOrdered(path string, t walkTarget, l *listing, ancestors []fileID) visitResult {
+	if r := w.visit(path, t.d, t.err); r != visitContinue || !t.descend {
+		return r
+	}
+
+	if l == nil {
+		l = w.prefetch(path)
+	}
+	<-l.done
+	if l.err != nil {
+		if r := w.visit(path, t.d, l.err); r == visitStop {
+			return r
+		}
+		return visitContinue
+	}
+
+	ancestors = w.c.descendants(ancestors, t)
+	paths := make([]string, len(l.infos))
+	targets := make([]walkTarget, len(l.infos))
+	for i, info := range l.infos {
+		paths[i] = joinPath(w.fs, path, info.Name())
+		targets[i] = w.c.prepare(paths[i], info, ancestors)
+	}
+
+	// Read ahead a window of the next subdirectories, the listings are
+	// released once walked or skipped
+	listings := make([]*listing, len(l.infos))
+	ahead, next := 0, 0
+	for i := range l.infos {
+		for ; next < len(l.infos) && (next <= i || ahead < w.opts.workers()); next++ {
+			if targets[next].descend {
+				listings[next] = w.prefetch(paths[next])
+				ahead++
+			}
+		}
+		sub := listings[i]
+		if sub != nil {
+			listings[i] = nil
+			ahead--
+		}
+		r := w.walkOrdered(paths[i], targets[i], sub, ancestors)
+		if r == visitStop {
+			return r
+		}
+		if r == visitSkip && !targets[i].d.IsDir() {
+			break
+		}
+	}
+	return visitContinue
+}
@@ -0,0 +1,348 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// makeWideTree creates directories with files below dir on the OS filesystem.
+func makeWideTree(t *testing.T) string {
+	root := t.TempDir()
+	fs := OS()
+	for i := 0; i < 5; i++ {
+		dir := filepath.Join(root, "dir"+strconv.Itoa(i))
+		if err := MkdirAll(fs, filepath.Join(dir, "sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 5; j++ {
+			touch(t, fs, filepath.Join(dir, "file"+strconv.Itoa(j)))
+			touch(t, fs, filepath.Join(dir, "sub", "file"+strconv.Itoa(j)))
+		}
+	}
+	return root
+}
+
+// walkDirPaths returns the paths visited by WalkDir.
+func walkDirPaths(t *testing.T, fs Filesystem, root string) []string {
+	var paths []string
+	err := WalkDir(fs, root, func(path string, d iofs.DirEntry, err error) error {
+		paths = append(paths, path)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+// collector collects the visited paths concurrently.
+type collector struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (c *collector) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = append(c.paths, path)
+}
+
+func (c *collector) sorted() []string {
+	sort.Strings(c.paths)
+	return c.paths
+}
+
+func TestParallelWalk(t *testing.T) {
+	root := makeWideTree(t)
+	fs := OS()
+	want := walkDirPaths(t, fs, root)
+
+	for _, opts := range []ParallelWalkOptions{{}, {Workers: 1}, {Workers: 3}} {
+		var c collector
+		err := ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			c.add(path)
+			return err
+		}, opts)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		sorted := append([]string(nil), want...)
+		sort.Strings(sorted)
+		if got := c.sorted(); !reflect.DeepEqual(got, sorted) {
+			t.Errorf("Walked %q, want %q", got, sorted)
+		}
+	}
+
+	var ordered []string
+	err := ParallelWalk(context.Background(), reverseFS{fs}, root, func(path string, d iofs.DirEntry, err error) error {
+		ordered = append(ordered, path)
+		return err
+	}, ParallelWalkOptions{Workers: 4, Ordered: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ordered, want) {
+		t.Errorf("Walked %q, want %q", ordered, want)
+	}
+}
+
+func TestParallelWalkSkip(t *testing.T) {
+	root := makeWideTree(t)
+	fs := OS()
+
+	for _, ordered := range []bool{false, true} {
+		var c collector
+		err := ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			c.add(path)
+			switch filepath.Base(path) {
+			case "sub":
+				return iofs.SkipDir
+			case "file3":
+				// Skips the remaining files of the directory
+				return iofs.SkipDir
+			}
+			return nil
+		}, ParallelWalkOptions{Workers: 2, Ordered: ordered})
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		for _, path := range c.paths {
+			if filepath.Base(filepath.Dir(path)) == "sub" {
+				t.Errorf("Skipped directory walked: %s", path)
+			}
+		}
+		if ordered {
+			for _, path := range c.paths {
+				if filepath.Base(path) == "file4" {
+					t.Errorf("Skipped file walked: %s", path)
+				}
+			}
+		}
+
+		var visits atomic.Int32
+		err = ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			if visits.Add(1) == 3 {
+				return iofs.SkipAll
+			}
+			return nil
+		}, ParallelWalkOptions{Workers: 1, Ordered: ordered})
+		if err != nil || visits.Load() != 3 {
+			t.Errorf("SkipAll: %d visits, %v", visits.Load(), err)
+		}
+	}
+}
+
+func TestParallelWalkErrors(t *testing.T) {
+	root := makeWideTree(t)
+	fs := OS()
+	errFile := errors.New("file error")
+
+	for _, ordered := range []bool{false, true} {
+		err := ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			if filepath.Base(path) == "file1" {
+				return errFile
+			}
+			return nil
+		}, ParallelWalkOptions{Workers: 1, Ordered: ordered})
+		if !errors.Is(err, errFile) {
+			t.Errorf("Expected file error, got %v", err)
+		}
+
+		var visits atomic.Int32
+		err = ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			visits.Add(1)
+			if filepath.Base(path) == "file1" {
+				return errors.New(path)
+			}
+			return nil
+		}, ParallelWalkOptions{Workers: 2, Ordered: ordered, ContinueOnError: true})
+		if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 10 {
+			t.Errorf("Expected 10 errors, got %v", err)
+		}
+		if visits.Load() != int32(len(walkDirPaths(t, fs, root))) {
+			t.Errorf("Walk not continued: %d visits", visits.Load())
+		}
+	}
+
+	err := ParallelWalk(context.Background(), fs, filepath.Join(root, "missing"), func(path string, d iofs.DirEntry, err error) error {
+		return err
+	}, ParallelWalkOptions{})
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestParallelWalkContext(t *testing.T) {
+	root := makeWideTree(t)
+	fs := OS()
+
+	for _, ordered := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var visits atomic.Int32
+		err := ParallelWalk(ctx, fs, root, func(path string, d iofs.DirEntry, err error) error {
+			if visits.Add(1) == 5 {
+				cancel()
+			}
+			return nil
+		}, ParallelWalkOptions{Workers: 1, Ordered: ordered})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if visits.Load() != 5 {
+			t.Errorf("Walk not stopped: %d visits", visits.Load())
+		}
+	}
+}
+
+func TestParallelWalkEntries(t *testing.T) {
+	root := makeWideTree(t)
+	fs := OS()
+	want := walkDirPaths(t, fs, root)
+
+	var got []string
+	for e := range ParallelWalkEntries(context.Background(), fs, root, ParallelWalkOptions{Ordered: true}) {
+		if e.Err != nil {
+			t.Errorf("Unexpected error: %s", e.Err)
+		}
+		got = append(got, e.Path)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walked %q, want %q", got, want)
+	}
+
+	// Stop reading early
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ParallelWalkEntries(ctx, fs, root, ParallelWalkOptions{})
+	<-ch
+	cancel()
+	for range ch {
+	}
+}
+
+// slowFS records the maximum number of concurrent ReadDir calls.
+type slowFS struct {
+	Filesystem
+	running, max atomic.Int32
+}
+
+func (fs *slowFS) ReadDir(path string) ([]os.FileInfo, error) {
+	n := fs.running.Add(1)
+	defer fs.running.Add(-1)
+	for {
+		m := fs.max.Load()
+		if n <= m || fs.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return fs.Filesystem.ReadDir(path)
+}
+
+func TestParallelWalkWorkers(t *testing.T) {
+	root := makeWideTree(t)
+
+	for _, ordered := range []bool{false, true} {
+		fs := &slowFS{Filesystem: OS()}
+		err := ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			return err
+		}, ParallelWalkOptions{Workers: 3, Ordered: ordered})
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if max := fs.max.Load(); max < 2 || max > 3 {
+			t.Errorf("Unexpected concurrency %d", max)
+		}
+	}
+}
+
+// countFS counts the ReadDir calls.
+type countFS struct {
+	Filesystem
+	reads atomic.Int32
+}
+
+func (fs *countFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fs.reads.Add(1)
+	return fs.Filesystem.ReadDir(path)
+}
+
+func TestParallelWalkReadAhead(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 50; i++ {
+		if err := OS().Mkdir(filepath.Join(root, "dir"+strconv.Itoa(i)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := &countFS{Filesystem: OS()}
+	var reads int32
+	err := ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+		if reads == 0 && path != root {
+			time.Sleep(20 * time.Millisecond)
+			reads = fs.reads.Load()
+		}
+		return err
+	}, ParallelWalkOptions{Workers: 2, Ordered: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	// The root and the window of the next subdirectories
+	if reads > 3 {
+		t.Errorf("Read %d directories ahead of the first entry", reads)
+	}
+	if n := fs.reads.Load(); n != 51 {
+		t.Errorf("Read %d directories, want 51", n)
+	}
+}
+
+func TestParallelWalkFollowSymlinks(t *testing.T) {
+	root := makeLinkTree(t)
+	fs := OS()
+	opts := WalkOptions{FollowSymlinks: true}
+	var want []string
+	err := WalkDirWithOptions(fs, root, func(path string, d iofs.DirEntry, err error) error {
+		if errors.Is(err, ErrFilesystemLoop) {
+			path += " loop"
+		}
+		want = append(want, path)
+		return nil
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ordered := range []bool{false, true} {
+		var c collector
+		err := ParallelWalk(context.Background(), fs, root, func(path string, d iofs.DirEntry, err error) error {
+			if errors.Is(err, ErrFilesystemLoop) {
+				path += " loop"
+			} else if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			c.add(path)
+			return nil
+		}, ParallelWalkOptions{WalkOptions: opts, Workers: 3, Ordered: ordered})
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		got, exp := c.paths, want
+		if !ordered {
+			got = c.sorted()
+			exp = append([]string(nil), want...)
+			sort.Strings(exp)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("Ordered %v: walked %q, want %q", ordered, got, exp)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package vfs
 
 import (
+	"os"
 	"strings"
+	"syscall"
 )
 
 // SplitPath splits the given path in segments:
@@ -29,3 +31,69 @@ func SplitPath(path string, sep string) []string {
 
 	return parts
 }
+
+// maxSymlinks is the maximum number of symbolic links followed
+// while resolving a path, like on Linux.
+const maxSymlinks = 40
+
+// ResolvePath resolves the symbolic links of path on the given Filesystem
+// and returns the clean absolute path. Relative paths are resolved from
+// the root, ".." segments never leave it.
+// A symbolic link in the last segment is only followed if follow is set.
+// Resolving stops at the first segment which does not exist or can not be
+// read, so the operation on the returned path reports the error, unless a
+// ".." segment follows it.
+// Symbolic links can only be resolved if fs implements ReadlinkFS.
+// If there is an error, it will be of type *os.PathError.
+func ResolvePath(fs Filesystem, path string, follow bool) (string, error) {
+	sep := string(fs.PathSeparator())
+	var resolved []string
+	segs := strings.Split(path, sep)
+	links := 0
+	missing := false
+	for len(segs) > 0 {
+		seg := segs[0]
+		segs = segs[1:]
+		switch seg {
+		case "", ".":
+			continue
+		case "..":
+			if missing {
+				// Symbolic links may hide behind the missing directory
+				return "", &os.PathError{Op: "resolve", Path: path, Err: syscall.ENOENT}
+			}
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+		resolved = append(resolved, seg)
+		if missing || len(segs) == 0 && !follow {
+			continue
+		}
+
+		current := sep + strings.Join(resolved, sep)
+		fi, err := fs.Lstat(current)
+		if err != nil {
+			// The rest of the path can not contain symbolic links
+			missing = true
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", &os.PathError{Op: "resolve", Path: path, Err: syscall.ELOOP}
+		}
+		target, err := Readlink(fs, current)
+		if err != nil {
+			return "", err
+		}
+		resolved = resolved[:len(resolved)-1]
+		if strings.HasPrefix(target, sep) {
+			resolved = nil
+		}
+		segs = append(strings.Split(target, sep), segs...)
+	}
+	return sep + strings.Join(resolved, sep), nil
+}
@@ -1,7 +1,10 @@
 package vfs
 
 import (
+	"errors"
+	"os"
 	"reflect"
+	"syscall"
 	"testing"
 )
 
@@ -26,3 +29,75 @@ func TestSplitPath(t *testing.T) {
 		t.Errorf("Invalid path: %q", p)
 	}
 }
+
+// linkFS is a Filesystem with the given symbolic links and directories.
+type linkFS struct {
+	Filesystem
+	links map[string]string
+	dirs  map[string]bool
+}
+
+func (fs linkFS) Lstat(name string) (os.FileInfo, error) {
+	if _, ok := fs.links[name]; ok {
+		return DumFileInfo{IName: name, IMode: os.ModeSymlink | 0777}, nil
+	}
+	if fs.dirs[name] {
+		return DumFileInfo{IName: name, IDir: true, IMode: os.ModeDir | 0777}, nil
+	}
+	return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs linkFS) Readlink(name string) (string, error) {
+	return fs.links[name], nil
+}
+
+func TestResolvePath(t *testing.T) {
+	fs := linkFS{
+		Filesystem: Dummy(errors.New("dummy")),
+		links: map[string]string{
+			"/abs":    "/dir",
+			"/rel":    "dir/sub",
+			"/up":     "../../dir",
+			"/dir/up": "..",
+			"/loop":   "loop",
+		},
+		dirs: map[string]bool{"/dir": true, "/dir/sub": true},
+	}
+
+	tests := []struct {
+		path   string
+		follow bool
+		want   string
+	}{
+		{"", true, "/"},
+		{"..", true, "/"},
+		{"../../x", true, "/x"},
+		{"dir/../../x", true, "/x"},
+		{"/dir/./sub/", true, "/dir/sub"},
+		{"abs/sub", true, "/dir/sub"},
+		{"abs", true, "/dir"},
+		{"abs", false, "/abs"},
+		{"rel/x", true, "/dir/sub/x"},
+		{"up/sub", true, "/dir/sub"},
+		{"dir/up/abs", true, "/dir"},
+		{"missing/x", true, "/missing/x"},
+	}
+	for _, test := range tests {
+		got, err := ResolvePath(fs, test.path, test.follow)
+		if err != nil {
+			t.Errorf("ResolvePath(%q, %v): Unexpected error: %s", test.path, test.follow, err)
+		} else if got != test.want {
+			t.Errorf("ResolvePath(%q, %v) = %q, want %q", test.path, test.follow, got, test.want)
+		}
+	}
+
+	if _, err := ResolvePath(fs, "loop", true); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("Expected ELOOP, got %v", err)
+	}
+	if _, err := ResolvePath(fs, "missing/../abs", true); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if p, err := ResolvePath(fs, "loop", false); err != nil || p != "/loop" {
+		t.Errorf("Unexpected result %q, %v", p, err)
+	}
+}
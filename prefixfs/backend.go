@@ -0,0 +1,23 @@
+package prefixfs
+
+import (
+	"fmt"
+
+	"github.com/lordofscripts/vfs"
+)
+
+func init() {
+	vfs.RegisterBackend("prefix", newBackend)
+}
+
+// newBackend creates a FS for the "prefix" backend, which prefixes all
+// paths on the filesystem of the OS with the source.
+func newBackend(source string, options map[string]string) (vfs.Filesystem, string, error) {
+	if len(options) > 0 {
+		return nil, "", fmt.Errorf("prefix: unsupported options %v", options)
+	}
+	if source == "" {
+		return nil, "", fmt.Errorf("prefix: missing source")
+	}
+	return Create(vfs.OS(), source), "/", nil
+}
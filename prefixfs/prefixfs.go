@@ -1,12 +1,31 @@
 package prefixfs
 
 import (
+	"context"
+	"io"
 	"os"
+	filepath "path"
+	"strings"
+	"time"
 
 	"github.com/lordofscripts/vfs"
 )
 
-// A FS that prefixes the path in each vfs.Filesystem operation.
+// A FS that confines each vfs.Filesystem operation to the directory Prefix
+// of the underlying filesystem, like a chroot.
+//
+// Paths are cleaned lexically, ".." never leaves the prefix. Symbolic links
+// are resolved inside the prefix: absolute targets are relative to it and
+// are stored with the prefix, so they point to the same file on the
+// underlying filesystem. Symbolic links can only be resolved if the
+// underlying filesystem implements vfs.ReadlinkFS.
+// Names of files and paths of errors are relative to the prefix.
+//
+// The confinement is not safe against concurrent modifications of the
+// underlying filesystem: symbolic links are resolved before an operation
+// runs, so a directory replaced by a symbolic link in between, e.g. by
+// another process on the OS filesystem, can lead outside of the prefix.
+// Only rely on it, if nobody else can write below the prefix.
 type FS struct {
 	vfs.Filesystem
 
@@ -21,57 +40,247 @@ func Create(root vfs.Filesystem, prefix string) *FS {
 }
 
 // PrefixPath returns path with the prefix prefixed.
+// The path is cleaned lexically and can not leave the prefix.
+// Symbolic links are not resolved.
 func (fs *FS) PrefixPath(path string) string {
-	return fs.Prefix + string(fs.PathSeparator()) + path
+	return filepath.Join(fs.Prefix, filepath.Clean("/"+path))
+}
+
+// root returns the clean prefix.
+func (fs *FS) root() string {
+	return filepath.Join(fs.Prefix, "/")
+}
+
+// innerPath returns the path inside the prefix of a path on the
+// underlying filesystem. Paths outside of the prefix are returned unchanged.
+func (fs *FS) innerPath(path string) string {
+	root := fs.root()
+	switch {
+	case root == "/":
+		return path
+	case path == root:
+		return "/"
+	case strings.HasPrefix(path, root+"/"):
+		return path[len(root):]
+	}
+	return path
+}
+
+// innerError reports the paths of PathErrors and LinkErrors inside the prefix.
+func (fs *FS) innerError(err error) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.PathError{Op: e.Op, Path: fs.innerPath(e.Path), Err: e.Err}
+	case *os.LinkError:
+		return &os.LinkError{Op: e.Op, Old: fs.innerPath(e.Old), New: fs.innerPath(e.New), Err: e.Err}
+	}
+	return err
+}
+
+// resolve resolves the symbolic links of path inside the prefix and returns
+// the path on the underlying filesystem, see vfs.ResolvePath.
+func (fs *FS) resolve(path string, follow bool) (string, error) {
+	path, err := vfs.ResolvePath(jail{fs}, path, follow)
+	if err != nil {
+		return "", err
+	}
+	return fs.PrefixPath(path), nil
+}
+
+// jail resolves symbolic links inside the prefix.
+type jail struct {
+	*FS
+}
+
+func (j jail) Lstat(name string) (os.FileInfo, error) {
+	return j.Filesystem.Lstat(j.PrefixPath(name))
+}
+
+func (j jail) Readlink(name string) (string, error) {
+	target, err := vfs.Readlink(j.Filesystem, j.PrefixPath(name))
+	return j.innerPath(target), j.innerError(err)
+}
+
+// WithContext implements vfs.FilesystemContext
+// and binds ctx to the underlying filesystem.
+func (fs *FS) WithContext(ctx context.Context) vfs.Filesystem {
+	return Create(vfs.WithContext(ctx, fs.Filesystem), fs.Prefix)
 }
 
 // PathSeparator implements vfs.Filesystem.
 func (fs *FS) PathSeparator() uint8 { return fs.Filesystem.PathSeparator() }
 
 // OpenFile implements vfs.Filesystem.
+// Name of the returned file is the given name.
 func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
-	return fs.Filesystem.OpenFile(fs.PrefixPath(name), flag, perm)
+	path, err := fs.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fs.Filesystem.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, fs.innerError(err)
+	}
+	return file{File: f, name: name}, nil
 }
 
 // Remove implements vfs.Filesystem.
 func (fs *FS) Remove(name string) error {
-	return fs.Filesystem.Remove(fs.PrefixPath(name))
+	path, err := fs.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	return fs.innerError(fs.Filesystem.Remove(path))
 }
 
+// RemoveAll implements vfs.Filesystem.
+// The prefix directory itself is not removed.
 func (fs *FS) RemoveAll(path string) error {
-	return fs.Filesystem.RemoveAll(fs.PrefixPath(path))
+	path, err := fs.resolve(path, false)
+	if err != nil {
+		return err
+	}
+	if path != fs.root() {
+		return fs.innerError(fs.Filesystem.RemoveAll(path))
+	}
+
+	fis, err := fs.Filesystem.ReadDir(path)
+	if err != nil {
+		return fs.innerError(err)
+	}
+	for _, fi := range fis {
+		if err := fs.Filesystem.RemoveAll(filepath.Join(path, fi.Name())); err != nil {
+			return fs.innerError(err)
+		}
+	}
+	return nil
 }
 
 // Rename implements vfs.Filesystem.
 func (fs *FS) Rename(oldpath, newpath string) error {
-	return fs.Filesystem.Rename(fs.PrefixPath(oldpath), fs.PrefixPath(newpath))
+	oldpath, err := fs.resolve(oldpath, false)
+	if err != nil {
+		return err
+	}
+	newpath, err = fs.resolve(newpath, false)
+	if err != nil {
+		return err
+	}
+	return fs.innerError(fs.Filesystem.Rename(oldpath, newpath))
 }
 
 // Mkdir implements vfs.Filesystem.
 func (fs *FS) Mkdir(name string, perm os.FileMode) error {
-	return fs.Filesystem.Mkdir(fs.PrefixPath(name), perm)
+	path, err := fs.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	return fs.innerError(fs.Filesystem.Mkdir(path, perm))
 }
 
+// MkdirAll implements vfs.Filesystem.
 func (fs *FS) MkdirAll(path string, perm os.FileMode) error {
-	return fs.Filesystem.MkdirAll(fs.PrefixPath(path), perm)
+	return vfs.MkdirAll(fs, path, perm)
 }
 
 // Symlink implements vfs.Filesystem.
+// Absolute targets are stored with the prefix.
 func (fs *FS) Symlink(oldname, newname string) error {
-	return fs.Filesystem.Symlink(fs.PrefixPath(oldname), fs.PrefixPath(newname))
+	path, err := fs.resolve(newname, false)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(oldname, "/") {
+		oldname = fs.PrefixPath(oldname)
+	}
+	return fs.innerError(fs.Filesystem.Symlink(oldname, path))
+}
+
+// Chmod implements vfs.ChmodFS.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	path, err := fs.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return fs.innerError(vfs.Chmod(fs.Filesystem, path, mode))
+}
+
+// Chtimes implements vfs.ChtimesFS.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	path, err := fs.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return fs.innerError(vfs.Chtimes(fs.Filesystem, path, atime, mtime))
+}
+
+// Readlink implements vfs.ReadlinkFS.
+// Absolute targets are returned without the prefix.
+func (fs *FS) Readlink(name string) (string, error) {
+	path, err := fs.resolve(name, false)
+	if err != nil {
+		return "", err
+	}
+	target, err := vfs.Readlink(fs.Filesystem, path)
+	if err != nil {
+		return "", fs.innerError(err)
+	}
+	return fs.innerPath(target), nil
 }
 
 // Stat implements vfs.Filesystem.
 func (fs *FS) Stat(name string) (os.FileInfo, error) {
-	return fs.Filesystem.Stat(fs.PrefixPath(name))
+	path, err := fs.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fs.Filesystem.Stat(path)
+	return fi, fs.innerError(err)
 }
 
 // Lstat implements vfs.Filesystem.
 func (fs *FS) Lstat(name string) (os.FileInfo, error) {
-	return fs.Filesystem.Lstat(fs.PrefixPath(name))
+	path, err := fs.resolve(name, false)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fs.Filesystem.Lstat(path)
+	return fi, fs.innerError(err)
 }
 
 // ReadDir implements vfs.Filesystem.
 func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
-	return fs.Filesystem.ReadDir(fs.PrefixPath(path))
+	path, err := fs.resolve(path, true)
+	if err != nil {
+		return nil, err
+	}
+	fis, err := fs.Filesystem.ReadDir(path)
+	return fis, fs.innerError(err)
+}
+
+// file reports the name given to OpenFile instead of the prefixed path.
+type file struct {
+	vfs.File
+	name string
+}
+
+// Name returns the name given to OpenFile.
+func (f file) Name() string {
+	return f.name
+}
+
+// ReadFrom uses the io.ReaderFrom of the wrapped file if available.
+func (f file) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := f.File.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(f.File, r)
+}
+
+// WriteTo uses the io.WriterTo of the wrapped file if available.
+func (f file) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := f.File.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, f.File)
 }
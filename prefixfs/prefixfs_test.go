@@ -1,8 +1,10 @@
 package prefixfs
 
 import (
+	"errors"
 	"os"
 	"reflect"
+	"syscall"
 	"testing"
 
 	"github.com/lordofscripts/vfs"
@@ -155,7 +157,7 @@ func TestStat(t *testing.T) {
 		t.Errorf("FileInfo: Mode not equal (fs:%v != root:%v)", fi.IsDir(), rfi.IsDir())
 	}
 
-	if fi.Sys() != rfi.Sys() {
+	if !reflect.DeepEqual(fi.Sys(), rfi.Sys()) {
 		t.Errorf("FileInfo: Sys not equal (fs:%v != root:%v)", fi.Sys(), rfi.Sys())
 	}
 }
@@ -200,7 +202,7 @@ func TestLstat(t *testing.T) {
 		t.Errorf("FileInfo: Mode not equal (fs:%v != root:%v)", fi.IsDir(), rfi.IsDir())
 	}
 
-	if fi.Sys() != rfi.Sys() {
+	if !reflect.DeepEqual(fi.Sys(), rfi.Sys()) {
 		t.Errorf("FileInfo: Sys not equal (fs:%v != root:%v)", fi.Sys(), rfi.Sys())
 	}
 }
@@ -237,3 +239,115 @@ func TestReadDir(t *testing.T) {
 		t.Error("ReadDir: slices not equal")
 	}
 }
+
+func TestBackend(t *testing.T) {
+	factory, ok := vfs.LookupBackend("prefix")
+	if !ok {
+		t.Fatalf("Backend not registered")
+	}
+	fs, root, err := factory("/srv", nil)
+	if err != nil || root != "/" || fs.(*FS).Prefix != "/srv" {
+		t.Errorf("Unexpected backend: %v %q %v", fs, root, err)
+	}
+	if _, _, err := factory("", nil); err == nil {
+		t.Errorf("Expected error without source")
+	}
+	if _, _, err := factory("/srv", map[string]string{"ro": ""}); err == nil {
+		t.Errorf("Expected error on unknown option")
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, prefixPath)
+	vfs.WriteFile(rfs, "/secret", []byte("secret"), 0666)
+	rfs.Mkdir("/outside", 0777)
+	rfs.Mkdir(prefix("outside"), 0777)
+	vfs.WriteFile(rfs, prefix("outside/file"), []byte("inside"), 0666)
+
+	if err := fs.Symlink("/outside", "abs"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := rfs.Symlink("/outside", prefix("hostabs")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := rfs.Symlink("../../outside", prefix("rel")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := fs.Symlink("loop", "loop"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	for _, name := range []string{"abs/file", "hostabs/file", "rel/file", "../outside/file", "outside/../../outside/file", "/../../outside/file"} {
+		data, err := vfs.ReadFile(fs, name)
+		if err != nil {
+			t.Errorf("ReadFile(%q): Unexpected error: %s", name, err)
+		} else if string(data) != "inside" {
+			t.Errorf("ReadFile(%q) escaped the prefix: %q", name, data)
+		}
+	}
+	for _, name := range []string{"../secret", "/../secret", "abs/../../secret", "missing/../../secret"} {
+		if _, err := vfs.ReadFile(fs, name); !os.IsNotExist(err) {
+			t.Errorf("ReadFile(%q): Expected not exist error, got %v", name, err)
+		}
+	}
+
+	if err := vfs.WriteFile(fs, "../../created", []byte{}, 0666); err != nil {
+		t.Errorf("WriteFile: Unexpected error: %s", err)
+	}
+	if _, err := rfs.Stat(prefix("created")); err != nil {
+		t.Errorf("File was not created inside the prefix: %s", err)
+	}
+
+	if target, err := fs.Readlink("abs"); err != nil || target != "/outside" {
+		t.Errorf("Readlink: %q, %v", target, err)
+	}
+	if target, err := vfs.Readlink(rfs, prefix("abs")); err != nil || target != prefix("outside") {
+		t.Errorf("Stored symlink: %q, %v", target, err)
+	}
+
+	if _, err := fs.Stat("loop"); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("Expected ELOOP, got %v", err)
+	}
+	if _, err := fs.Lstat("loop"); err != nil {
+		t.Errorf("Lstat: Unexpected error: %s", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, prefixPath)
+	fs.Mkdir("dir", 0777)
+
+	f, err := fs.OpenFile("/dir/../file", os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+	if f.Name() != "/dir/../file" {
+		t.Errorf("Unexpected name: %q", f.Name())
+	}
+
+	_, err = fs.Stat("/missing")
+	if pe, ok := err.(*os.PathError); !ok || pe.Path != "/missing" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	err = fs.Rename("/missing", "/other")
+	if le, ok := err.(*os.LinkError); !ok || le.Old != "/missing" || le.New != "/other" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestRemoveAllRoot(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, prefixPath)
+	vfs.WriteFile(fs, "file", []byte{}, 0666)
+	fs.Mkdir("dir", 0777)
+
+	if err := fs.RemoveAll("/.."); err != nil {
+		t.Errorf("RemoveAll: %v", err)
+	}
+	if fis, err := rfs.ReadDir(prefixPath); err != nil || len(fis) != 0 {
+		t.Errorf("Prefix not emptied: %v, %v", fis, err)
+	}
+}
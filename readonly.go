@@ -1,21 +1,29 @@
 package vfs
 
 import (
+	"context"
 	"errors"
+	"io"
 	"os"
+	"time"
 )
 
 // ReadOnly creates a readonly wrapper around the given filesystem.
-// It disables the following operations:
+// It disables every mutating operation:
 //
 //   - Create
-//   - Remove
+//   - Remove, RemoveAll
 //   - Rename
-//   - Mkdir
+//   - Mkdir, MkdirAll
+//   - Symlink
+//   - Chmod, Chtimes
 //
-// And disables OpenFile flags: os.O_CREATE, os.O_APPEND, os.O_WRONLY
+// And disables OpenFile flags: os.O_CREATE, os.O_APPEND, os.O_WRONLY, os.O_TRUNC
 //
-// OpenFile returns a File with disabled Write() method otherwise.
+// OpenFile returns a File with disabled Write, WriteAt, ReadFrom and
+// Truncate methods otherwise.
+//
+// Disabled operations return an *os.PathError wrapping ErrReadOnly.
 func ReadOnly(fs Filesystem) *RoFS {
 	return &RoFS{Filesystem: fs}
 }
@@ -26,26 +34,84 @@ type RoFS struct {
 	Filesystem
 }
 
-// ErrorReadOnly is returned on every disabled operation.
+// ErrReadOnly is wrapped by the errors of every disabled operation.
 var ErrReadOnly = errors.New("Filesystem is read-only")
 
-// Remove is disabled and returns ErrorReadOnly
+// readOnlyError returns the error of a disabled operation.
+func readOnlyError(op, path string) error {
+	return &os.PathError{Op: op, Path: path, Err: ErrReadOnly}
+}
+
+// PathSeparator returns the path separator of the underlying filesystem.
+func (fs RoFS) PathSeparator() uint8 {
+	return fs.Filesystem.PathSeparator()
+}
+
+// Remove is disabled and returns ErrReadOnly
 func (fs RoFS) Remove(name string) error {
-	return ErrReadOnly
+	return readOnlyError("remove", name)
+}
+
+// RemoveAll is disabled and returns ErrReadOnly
+func (fs RoFS) RemoveAll(path string) error {
+	return readOnlyError("removeall", path)
 }
 
-// Rename is disabled and returns ErrorReadOnly
+// Rename is disabled and returns ErrReadOnly
 func (fs RoFS) Rename(oldpath, newpath string) error {
-	return ErrReadOnly
+	return readOnlyError("rename", oldpath)
 }
 
-// Mkdir is disabled and returns ErrorReadOnly
+// Mkdir is disabled and returns ErrReadOnly
 func (fs RoFS) Mkdir(name string, perm os.FileMode) error {
-	return ErrReadOnly
+	return readOnlyError("mkdir", name)
+}
+
+// MkdirAll is disabled and returns ErrReadOnly
+func (fs RoFS) MkdirAll(path string, perm os.FileMode) error {
+	return readOnlyError("mkdir", path)
 }
 
+// Symlink is disabled and returns ErrReadOnly
 func (fs RoFS) Symlink(oldname, newname string) error {
-	return ErrReadOnly
+	return readOnlyError("symlink", newname)
+}
+
+// Chmod is disabled and returns ErrReadOnly
+func (fs RoFS) Chmod(name string, mode os.FileMode) error {
+	return readOnlyError("chmod", name)
+}
+
+// Chtimes is disabled and returns ErrReadOnly
+func (fs RoFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return readOnlyError("chtimes", name)
+}
+
+// WithContext implements FilesystemContext
+// and binds ctx to the underlying filesystem.
+func (fs RoFS) WithContext(ctx context.Context) Filesystem {
+	return ReadOnly(WithContext(ctx, fs.Filesystem))
+}
+
+// Readlink returns the destination of the named symbolic link
+// of the underlying filesystem, see vfs.Readlink.
+func (fs RoFS) Readlink(name string) (string, error) {
+	return Readlink(fs.Filesystem, name)
+}
+
+// Stat returns the FileInfo of the underlying filesystem.
+func (fs RoFS) Stat(name string) (os.FileInfo, error) {
+	return fs.Filesystem.Stat(name)
+}
+
+// Lstat returns the FileInfo of the underlying filesystem.
+func (fs RoFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.Filesystem.Lstat(name)
+}
+
+// ReadDir returns the directory content of the underlying filesystem.
+func (fs RoFS) ReadDir(path string) ([]os.FileInfo, error) {
+	return fs.Filesystem.ReadDir(path)
 }
 
 // Open opens the named file on the given Filesystem for reading.
@@ -56,26 +122,22 @@ func (fs RoFS) Open(name string) (File, error) {
 	return fs.OpenFile(name, os.O_RDONLY, 0)
 }
 
-// OpenFile returns ErrorReadOnly if flag contains os.O_CREATE, os.O_APPEND, os.O_WRONLY.
-// Otherwise it returns a read-only File with disabled Write(..) operation.
+// OpenFile returns ErrReadOnly if flag contains os.O_CREATE, os.O_APPEND,
+// os.O_WRONLY or os.O_TRUNC. Otherwise it returns a read-only File,
+// see ReadOnlyFile.
 func (fs RoFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
-	if flag&os.O_CREATE == os.O_CREATE {
-		return nil, ErrReadOnly
-	}
-	if flag&os.O_APPEND == os.O_APPEND {
-		return nil, ErrReadOnly
-	}
-	if flag&os.O_WRONLY == os.O_WRONLY {
-		return nil, ErrReadOnly
+	if flag&(os.O_CREATE|os.O_APPEND|os.O_WRONLY|os.O_TRUNC) != 0 {
+		return nil, readOnlyError("open", name)
 	}
 	f, err := fs.Filesystem.OpenFile(name, flag, perm)
 	if err != nil {
-		return ReadOnlyFile(f), err
+		return nil, err
 	}
 	return ReadOnlyFile(f), nil
 }
 
-// ReadOnlyFile wraps the given file and disables Write(..) operation.
+// ReadOnlyFile wraps the given file and disables Write, WriteAt, ReadFrom
+// and Truncate operations.
 func ReadOnlyFile(f File) File {
 	return &roFile{File: f}
 }
@@ -84,7 +146,61 @@ type roFile struct {
 	File
 }
 
-// Write is disabled and returns ErrorReadOnly
+// Name returns the name of the wrapped file.
+func (f roFile) Name() string {
+	return f.File.Name()
+}
+
+// Sync commits the wrapped file, which can not have been modified.
+func (f roFile) Sync() error {
+	return f.File.Sync()
+}
+
+// Read reads from the wrapped file.
+func (f roFile) Read(p []byte) (n int, err error) {
+	return f.File.Read(p)
+}
+
+// ReadAt reads from the wrapped file.
+func (f roFile) ReadAt(p []byte, off int64) (n int, err error) {
+	return f.File.ReadAt(p, off)
+}
+
+// Seek seeks the wrapped file.
+func (f roFile) Seek(offset int64, whence int) (int64, error) {
+	return f.File.Seek(offset, whence)
+}
+
+// Close closes the wrapped file.
+func (f roFile) Close() error {
+	return f.File.Close()
+}
+
+// Write is disabled and returns ErrReadOnly
 func (f roFile) Write(p []byte) (n int, err error) {
-	return 0, ErrReadOnly
+	return 0, readOnlyError("write", f.File.Name())
+}
+
+// WriteAt is disabled and returns ErrReadOnly
+func (f roFile) WriteAt(p []byte, off int64) (n int, err error) {
+	return 0, readOnlyError("write", f.File.Name())
+}
+
+// ReadFrom is disabled and returns ErrReadOnly
+func (f roFile) ReadFrom(r io.Reader) (n int64, err error) {
+	return 0, readOnlyError("write", f.File.Name())
+}
+
+// Truncate is disabled and returns ErrReadOnly
+func (f roFile) Truncate(size int64) error {
+	return readOnlyError("truncate", f.File.Name())
+}
+
+// WriteTo writes the remaining content of the file to w.
+// It uses the io.WriterTo of the wrapped file if available.
+func (f roFile) WriteTo(w io.Writer) (n int64, err error) {
+	if wt, ok := f.File.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, f.File)
 }
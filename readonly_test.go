@@ -2,8 +2,12 @@ package vfs
 
 import (
 	"errors"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -20,22 +24,22 @@ func TestROInterface(t *testing.T) {
 
 func TestROOpenFileFlags(t *testing.T) {
 	_, err := ro.OpenFile("name", os.O_CREATE, 0666)
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Create error expected")
 	}
 
 	_, err = ro.OpenFile("name", os.O_APPEND, 0666)
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Append error expected")
 	}
 
 	_, err = ro.OpenFile("name", os.O_WRONLY, 0666)
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("WROnly error expected")
 	}
 
 	_, err = ro.OpenFile("name", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Error expected")
 	}
 
@@ -48,28 +52,28 @@ func TestROOpenFileFlags(t *testing.T) {
 
 func TestRORemove(t *testing.T) {
 	err := ro.Remove("test")
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Remove error expected")
 	}
 }
 
 func TestRORename(t *testing.T) {
 	err := ro.Rename("old", "new")
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Rename error expected")
 	}
 }
 
 func TestMkDir(t *testing.T) {
 	err := ro.Mkdir("test", 0777)
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Mkdir error expected")
 	}
 }
 
 func TestROSymlink(t *testing.T) {
 	err := ro.Symlink("old", "new")
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Symlink error expected")
 	}
 }
@@ -92,10 +96,167 @@ func TestROOpenFileWrite(t *testing.T) {
 		t.Errorf("No OpenFile error expected: %s", err)
 	}
 	written, err := f.Write([]byte("test"))
-	if err != ErrReadOnly {
+	if !errors.Is(err, ErrReadOnly) {
 		t.Errorf("Error expected: %s", err)
 	}
 	if written > 0 {
 		t.Errorf("Written expected 0: %d", written)
 	}
+	if _, err := f.WriteAt([]byte("test"), 0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("WriteAt error expected: %s", err)
+	}
+	if _, err := io.Copy(f, strings.NewReader("test")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("ReadFrom error expected: %s", err)
+	}
+	// Reading is passed through, dummy error is returned
+	if _, err := io.Copy(io.Discard, f); err != errDummy {
+		t.Errorf("Expected dummy error: %s", err)
+	}
+}
+
+func TestROTruncate(t *testing.T) {
+	roWriteMock := ReadOnly(writeDummyFS{Filesystem: Dummy(errDummy)})
+
+	if _, err := roWriteMock.OpenFile("name", os.O_RDWR|os.O_TRUNC, 0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Truncate error expected: %v", err)
+	}
+	f, err := roWriteMock.OpenFile("name", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("No OpenFile error expected: %s", err)
+	}
+	if err := f.Truncate(0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Truncate error expected: %v", err)
+	}
+}
+
+func TestRORemoveAllMkdirAll(t *testing.T) {
+	if err := ro.RemoveAll("/"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("RemoveAll error expected: %v", err)
+	}
+	if err := ro.MkdirAll("/a/b", 0777); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("MkdirAll error expected: %v", err)
+	}
+	if err := Chmod(ro, "/", 0777); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Chmod error expected: %v", err)
+	}
+	if err := Chtimes(ro, "/", time.Now(), time.Now()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Chtimes error expected: %v", err)
+	}
+}
+
+// recordingFS records the called methods of its filesystem and files.
+type recordingFS struct {
+	called map[string]bool
+}
+
+func (fs recordingFS) record(method string) error {
+	fs.called[method] = true
+	return errDummy
+}
+
+func (fs recordingFS) PathSeparator() uint8 { fs.record("PathSeparator"); return '/' }
+func (fs recordingFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.record("OpenFile")
+	return recordingFile{File: DummyFile(errDummy), fs: fs}, nil
+}
+func (fs recordingFS) Remove(name string) error                  { return fs.record("Remove") }
+func (fs recordingFS) RemoveAll(path string) error               { return fs.record("RemoveAll") }
+func (fs recordingFS) Rename(oldpath, newpath string) error      { return fs.record("Rename") }
+func (fs recordingFS) Mkdir(name string, perm os.FileMode) error { return fs.record("Mkdir") }
+func (fs recordingFS) MkdirAll(path string, perm os.FileMode) error {
+	return fs.record("MkdirAll")
+}
+func (fs recordingFS) Symlink(oldname, newname string) error { return fs.record("Symlink") }
+func (fs recordingFS) Chmod(name string, mode os.FileMode) error {
+	return fs.record("Chmod")
+}
+func (fs recordingFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return fs.record("Chtimes")
+}
+func (fs recordingFS) Readlink(name string) (string, error) { return "", fs.record("Readlink") }
+func (fs recordingFS) Stat(name string) (os.FileInfo, error) {
+	return nil, fs.record("Stat")
+}
+func (fs recordingFS) Lstat(name string) (os.FileInfo, error) {
+	return nil, fs.record("Lstat")
+}
+func (fs recordingFS) ReadDir(path string) ([]os.FileInfo, error) {
+	return nil, fs.record("ReadDir")
+}
+
+type recordingFile struct {
+	File
+	fs recordingFS
+}
+
+func (f recordingFile) Name() string                { f.fs.record("File.Name"); return "name" }
+func (f recordingFile) Sync() error                 { return f.fs.record("File.Sync") }
+func (f recordingFile) Truncate(int64) error        { return f.fs.record("File.Truncate") }
+func (f recordingFile) Read(p []byte) (int, error)  { return 0, f.fs.record("File.Read") }
+func (f recordingFile) Write(p []byte) (int, error) { return 0, f.fs.record("File.Write") }
+func (f recordingFile) Close() error                { return f.fs.record("File.Close") }
+func (f recordingFile) ReadAt(p []byte, off int64) (int, error) {
+	return 0, f.fs.record("File.ReadAt")
+}
+func (f recordingFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, f.fs.record("File.WriteAt")
+}
+func (f recordingFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, f.fs.record("File.Seek")
+}
+
+// callZero calls the method with zero arguments and returns its error.
+func callZero(m reflect.Value) error {
+	args := make([]reflect.Value, m.Type().NumIn())
+	for i := range args {
+		args[i] = reflect.Zero(m.Type().In(i))
+	}
+	out := m.Call(args)
+	if len(out) == 0 {
+		return nil
+	}
+	err, _ := out[len(out)-1].Interface().(error)
+	return err
+}
+
+// TestROAllMethods guards against mutating methods passing through RoFS.
+// Every method, which is not known to be read-only, must be disabled.
+func TestROAllMethods(t *testing.T) {
+	readMethods := map[string]bool{
+		"PathSeparator": true, "OpenFile": true, "Open": true, "Readlink": true,
+		"Stat": true, "Lstat": true, "ReadDir": true, "WithContext": true,
+		"File.Name": true, "File.Sync": true, "File.Read": true, "File.ReadAt": true,
+		"File.Seek": true, "File.Close": true, "File.WriteTo": true,
+	}
+	check := func(name string, m reflect.Value, called map[string]bool) {
+		clear(called)
+		err := callZero(m)
+		if readMethods[name] {
+			return
+		}
+		for c := range called {
+			if !readMethods[c] {
+				t.Errorf("%s: Passed through to the underlying %s", name, c)
+			}
+		}
+		if _, ok := err.(*os.PathError); !ok || !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: Expected *os.PathError wrapping ErrReadOnly, got %#v", name, err)
+		}
+	}
+
+	base := recordingFS{called: make(map[string]bool)}
+	fs := ReadOnly(base)
+	v := reflect.ValueOf(fs)
+	for i := 0; i < v.NumMethod(); i++ {
+		check(v.Type().Method(i).Name, v.Method(i), base.called)
+	}
+
+	f, err := fs.OpenFile("name", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("No OpenFile error expected: %s", err)
+	}
+	v = reflect.ValueOf(f)
+	for i := 0; i < v.NumMethod(); i++ {
+		check("File."+v.Type().Method(i).Name, v.Method(i), base.called)
+	}
 }
@@ -0,0 +1,70 @@
+package vfs
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// SysInfo is the portable stat structure returned by FileInfo.Sys() of the
+// virtual filesystems of this package and its subpackages. OsFS returns the
+// values of package os, which SysInfoOf converts. It identifies a file by
+// its device and inode number and holds the metadata not covered by
+// os.FileInfo. Timestamps which are not known are zero.
+type SysInfo struct {
+	Dev   uint64 // device of the filesystem holding the file
+	Ino   uint64 // inode number, unique per Dev, 0 if unknown
+	Nlink uint64 // number of hard links
+	Uid   int    // user id of the owner
+	Gid   int    // group id of the owner
+
+	Atime time.Time // time of the last access
+	Mtime time.Time // time of the last modification of the content
+	Ctime time.Time // time of the last change of the metadata
+	Btime time.Time // time of the creation (birth)
+
+	// Raw is the backend specific value, e.g. the *syscall.Stat_t of OsFS
+	Raw any
+}
+
+// SysInfoOf returns the SysInfo of fi. It converts values returned by
+// os.Stat if necessary. If fi does not provide any, nil is returned.
+func SysInfoOf(fi os.FileInfo) *SysInfo {
+	switch sys := fi.Sys().(type) {
+	case *SysInfo:
+		return sys
+	case nil:
+		return nil
+	}
+	return osSysInfo(fi)
+}
+
+// lastDev is the last device number given to a virtual filesystem.
+var lastDev atomic.Uint64
+
+// NewDev returns a unique device number for an instance of a virtual
+// filesystem. The numbers have the highest bit set, so they do not collide
+// with the device numbers of the OS.
+func NewDev() uint64 {
+	return 1<<63 | lastDev.Add(1)
+}
+
+// SameFile reports whether the files a and b on fs are the same file.
+// Symbolic links are followed. Files are compared by device and inode
+// number. If fs does not provide them, os.SameFile is used.
+// If there is an error, it will be of type *os.PathError.
+func SameFile(fs Filesystem, a, b string) (bool, error) {
+	fa, err := fs.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	fb, err := fs.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	sa, sb := SysInfoOf(fa), SysInfoOf(fb)
+	if sa == nil || sb == nil || sa.Ino == 0 || sb.Ino == 0 {
+		return os.SameFile(fa, fb), nil
+	}
+	return sa.Dev == sb.Dev && sa.Ino == sb.Ino, nil
+}
@@ -0,0 +1,26 @@
+package vfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// osSysInfo converts the *syscall.Stat_t of fi.
+func osSysInfo(fi os.FileInfo) *SysInfo {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return &SysInfo{Nlink: 1, Mtime: fi.ModTime(), Raw: fi.Sys()}
+	}
+	return &SysInfo{
+		Dev:   uint64(st.Dev),
+		Ino:   uint64(st.Ino),
+		Nlink: uint64(st.Nlink),
+		Uid:   int(st.Uid),
+		Gid:   int(st.Gid),
+		Atime: time.Unix(st.Atim.Unix()),
+		Mtime: time.Unix(st.Mtim.Unix()),
+		Ctime: time.Unix(st.Ctim.Unix()),
+		Raw:   st,
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package vfs
+
+import (
+	"os"
+)
+
+// osSysInfo returns a SysInfo with the modification time of fi only.
+func osSysInfo(fi os.FileInfo) *SysInfo {
+	return &SysInfo{Nlink: 1, Mtime: fi.ModTime(), Raw: fi.Sys()}
+}
@@ -0,0 +1,89 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOSSysInfo(t *testing.T) {
+	fs := OS()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	if err := WriteFile(fs, a, []byte("a"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	fi, err := fs.Stat(a)
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	// Sys returns the value of package os
+	if _, ok := fi.Sys().(*SysInfo); ok {
+		t.Errorf("Sys: expected the native value, got %T", fi.Sys())
+	}
+	sys := SysInfoOf(fi)
+	if sys == nil {
+		t.Fatalf("SysInfoOf: expected *SysInfo")
+	}
+	if sys.Raw != fi.Sys() {
+		t.Errorf("Raw: expected %T, got %T", fi.Sys(), sys.Raw)
+	}
+	if sys.Nlink != 1 || !sys.Mtime.Equal(fi.ModTime()) || sys.Raw == nil {
+		t.Errorf("Invalid SysInfo: %+v", sys)
+	}
+	if runtime.GOOS != "windows" && sys.Ino == 0 {
+		t.Errorf("Ino expected: %+v", sys)
+	}
+
+	fis, err := fs.ReadDir(dir)
+	if err != nil || len(fis) != 1 {
+		t.Fatalf("ReadDir: %v %s", fis, err)
+	}
+	if s := SysInfoOf(fis[0]); s == nil || s.Ino != sys.Ino {
+		t.Errorf("Invalid SysInfo of ReadDir: %+v", s)
+	}
+}
+
+func TestOSSameFile(t *testing.T) {
+	fs := OS()
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	for _, name := range []string{a, b} {
+		if err := WriteFile(fs, name, nil, 0666); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	link := filepath.Join(dir, "link")
+	if err := fs.Symlink(a, link); err != nil {
+		t.Skipf("Symlink: %s", err)
+	}
+
+	if same, err := SameFile(fs, a, link); err != nil || !same {
+		t.Errorf("Expected same file: %v %s", same, err)
+	}
+	if same, err := SameFile(fs, a, b); err != nil || same {
+		t.Errorf("Expected different files: %v %s", same, err)
+	}
+	if _, err := SameFile(fs, a, filepath.Join(dir, "nonexisting")); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error: %s", err)
+	}
+}
+
+func TestSysInfoOf(t *testing.T) {
+	if sys := SysInfoOf(DumFileInfo{}); sys != nil {
+		t.Errorf("Expected nil: %+v", sys)
+	}
+	sys := &SysInfo{Ino: 1}
+	if s := SysInfoOf(DumFileInfo{ISys: sys}); s != sys {
+		t.Errorf("Expected %+v: %+v", sys, s)
+	}
+}
+
+func TestNewDev(t *testing.T) {
+	d1, d2 := NewDev(), NewDev()
+	if d1 == d2 || d1>>63 != 1 {
+		t.Errorf("Invalid devices: %x %x", d1, d2)
+	}
+}
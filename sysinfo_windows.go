@@ -0,0 +1,23 @@
+package vfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// osSysInfo converts the *syscall.Win32FileAttributeData of fi.
+// Windows does not report inode numbers with it, so Ino is 0.
+func osSysInfo(fi os.FileInfo) *SysInfo {
+	d, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return &SysInfo{Nlink: 1, Mtime: fi.ModTime(), Raw: fi.Sys()}
+	}
+	return &SysInfo{
+		Nlink: 1,
+		Atime: time.Unix(0, d.LastAccessTime.Nanoseconds()),
+		Mtime: time.Unix(0, d.LastWriteTime.Nanoseconds()),
+		Btime: time.Unix(0, d.CreationTime.Nanoseconds()),
+		Raw:   d,
+	}
+}
@@ -1,17 +1,49 @@
 package vfs
 
 import (
+	"errors"
+	iofs "io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 )
 
+// ErrFilesystemLoop is reported by walks following symbolic links
+// for links leading to a directory, which is already being walked.
+var ErrFilesystemLoop = errors.New("Filesystem loop detected")
+
+// WalkOptions configure the traversal of WalkDirWithOptions and ParallelWalk.
+type WalkOptions struct {
+	// Unsorted walks the entries of a directory in the order returned by
+	// ReadDir instead of sorting them by name, e.g. for huge directories.
+	Unsorted bool
+
+	// FollowSymlinks follows symbolic links like find -L. The links are
+	// reported with the entry of their target and links to directories are
+	// walked like directories. Broken links are reported as links.
+	// A link leading to a directory on the path from the root is reported
+	// to the walk func with an error wrapping ErrFilesystemLoop and not
+	// followed. Directories are identified by the device and inode numbers
+	// of their SysInfo or, if the filesystem does not provide them,
+	// by their paths resolved by ResolvePath.
+	FollowSymlinks bool
+
+	// OneFilesystem does not descend into directories on other devices than
+	// the root like find -xdev, e.g. into other mounts of a MountFS.
+	// Devices are compared by SysInfo, directories without device are
+	// considered on the device of the root.
+	OneFilesystem bool
+}
+
 // Walk walks the file tree rooted at root, calling walkFunc for each file or
 // directory in the tree, including root. All errors that arise visiting files
 // and directories are filtered by walkFn. The files are walked in lexical
 // order, which makes the output deterministic but means that for very
 // large directories Walk can be inefficient.
+// The FileInfo of the entries are the results of ReadDir, so every file
+// is stat'ed at most once.
 // Walk does not follow symbolic links.
 func Walk(fs Filesystem, root string, walkFunc filepath.WalkFunc) error {
 	info, err := fs.Lstat(root)
@@ -26,19 +58,23 @@ func Walk(fs Filesystem, root string, walkFunc filepath.WalkFunc) error {
 	return err
 }
 
-// readDirNames reads the directory named by dirname and returns
-// a sorted list of directory entries.
-func readDirNames(fs Filesystem, dirname string) ([]string, error) {
+// readDir reads the directory named by dirname and returns
+// its entries, sorted by name unless unsorted is set.
+func readDir(fs Filesystem, dirname string, unsorted bool) ([]os.FileInfo, error) {
 	infos, err := fs.ReadDir(dirname)
 	if err != nil {
 		return nil, err
 	}
-	names := make([]string, 0, len(infos))
-	for _, info := range infos {
-		names = append(names, info.Name())
+	if !unsorted {
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
 	}
-	sort.Strings(names)
-	return names, nil
+	return infos, nil
+}
+
+// joinPath returns the path of the entry name in the directory dir.
+func joinPath(fs Filesystem, dir, name string) string {
+	sep := string(fs.PathSeparator())
+	return strings.TrimSuffix(dir, sep) + sep + name
 }
 
 // walk recursively descends path, calling walkFunc.
@@ -47,7 +83,7 @@ func walk(fs Filesystem, path string, info os.FileInfo, walkFunc filepath.WalkFu
 		return walkFunc(path, info, nil)
 	}
 
-	names, err := readDirNames(fs, path)
+	infos, err := readDir(fs, path, false)
 	err1 := walkFunc(path, info, err)
 	// If err != nil, walk can't walk into this directory.
 	// err1 != nil means walkFn want walk to skip this directory or stop walking.
@@ -60,20 +96,168 @@ func walk(fs Filesystem, path string, info os.FileInfo, walkFunc filepath.WalkFu
 		return err1
 	}
 
-	for _, name := range names {
-		filename := strings.Join([]string{path, name}, string(fs.PathSeparator()))
-		fileInfo, err := fs.Lstat(filename)
+	for _, fileInfo := range infos {
+		err = walk(fs, joinPath(fs, path, fileInfo.Name()), fileInfo, walkFunc)
 		if err != nil {
-			if err := walkFunc(filename, fileInfo, err); err != nil && err != filepath.SkipDir {
+			if !fileInfo.IsDir() || err != filepath.SkipDir {
 				return err
 			}
-		} else {
-			err = walk(fs, filename, fileInfo, walkFunc)
-			if err != nil {
-				if !fileInfo.IsDir() || err != filepath.SkipDir {
-					return err
-				}
+		}
+	}
+	return nil
+}
+
+// WalkDir walks the file tree rooted at root like fs.WalkDir, calling fn
+// for each file or directory in the tree, including root.
+// The fs.DirEntry values are built from the results of ReadDir, so no
+// entry is stat'ed again. fn may return fs.SkipDir to skip a directory,
+// or the remaining entries of the directory of a file, and fs.SkipAll to
+// stop walking. The files are walked in lexical order.
+// WalkDir does not follow symbolic links.
+func WalkDir(fs Filesystem, root string, fn iofs.WalkDirFunc) error {
+	return WalkDirWithOptions(fs, root, fn, WalkOptions{})
+}
+
+// WalkDirWithOptions walks the file tree rooted at root like WalkDir
+// according to the given options.
+func WalkDirWithOptions(fs Filesystem, root string, fn iofs.WalkDirFunc, opts WalkOptions) error {
+	c, info, err := newWalkConfig(fs, root, opts)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = c.walkDir(root, c.prepare(root, info, nil), fn, nil)
+	}
+	if err == iofs.SkipDir || err == iofs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkConfig holds the options of a walk and the state of its root.
+type walkConfig struct {
+	fs      Filesystem
+	opts    WalkOptions
+	rootDev uint64 // 0 if unknown
+}
+
+// newWalkConfig returns the configuration of a walk and the FileInfo of root.
+func newWalkConfig(fs Filesystem, root string, opts WalkOptions) (*walkConfig, os.FileInfo, error) {
+	stat := fs.Lstat
+	if opts.FollowSymlinks {
+		stat = fs.Stat
+	}
+	info, err := stat(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &walkConfig{fs: fs, opts: opts}
+	if sys := SysInfoOf(info); sys != nil {
+		c.rootDev = sys.Dev
+	}
+	return c, info, nil
+}
+
+// fileID identifies a directory for the loop detection.
+type fileID struct {
+	dev, ino uint64
+	path     string // resolved path if the inode is unknown
+}
+
+// dirID returns the identity of the directory at path.
+func (c *walkConfig) dirID(path string, info os.FileInfo) fileID {
+	if sys := SysInfoOf(info); sys != nil && sys.Ino != 0 {
+		return fileID{dev: sys.Dev, ino: sys.Ino}
+	}
+	if resolved, err := ResolvePath(c.fs, path, true); err == nil {
+		path = resolved
+	}
+	return fileID{path: path}
+}
+
+// walkTarget is an entry prepared for walking according to the options.
+type walkTarget struct {
+	d       iofs.DirEntry
+	descend bool   // walk the entries of the directory
+	err     error  // error reported to the walk func, e.g. a loop
+	id      fileID // identity of the directory if links are followed
+}
+
+// prepare follows a symbolic link if demanded and decides whether to
+// descend into the entry. ancestors are the identities of the directories
+// on the path from the root.
+func (c *walkConfig) prepare(path string, info os.FileInfo, ancestors []fileID) walkTarget {
+	followed := false
+	if c.opts.FollowSymlinks && info.Mode()&os.ModeSymlink != 0 {
+		if fi, err := c.fs.Stat(path); err == nil {
+			info, followed = namedInfo{FileInfo: fi, name: info.Name()}, true
+		}
+	}
+	t := walkTarget{d: iofs.FileInfoToDirEntry(info), descend: info.IsDir()}
+	if !t.descend {
+		return t
+	}
+	if c.opts.OneFilesystem && c.rootDev != 0 {
+		if sys := SysInfoOf(info); sys != nil && sys.Dev != 0 && sys.Dev != c.rootDev {
+			t.descend = false
+			return t
+		}
+	}
+	if c.opts.FollowSymlinks {
+		t.id = c.dirID(path, info)
+		if followed && slices.Contains(ancestors, t.id) {
+			t.descend = false
+			t.err = &os.PathError{Op: "walk", Path: path, Err: ErrFilesystemLoop}
+		}
+	}
+	return t
+}
+
+// namedInfo reports the name of a symbolic link for the FileInfo of its target.
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi namedInfo) Name() string { return fi.name }
+
+// descendants returns the ancestors of the entries of the directory t.
+func (c *walkConfig) descendants(ancestors []fileID, t walkTarget) []fileID {
+	if !c.opts.FollowSymlinks {
+		return nil
+	}
+	return append(ancestors[:len(ancestors):len(ancestors)], t.id)
+}
+
+// walkDir recursively descends path, calling fn.
+func (c *walkConfig) walkDir(path string, t walkTarget, fn iofs.WalkDirFunc, ancestors []fileID) error {
+	if err := fn(path, t.d, t.err); err != nil || !t.descend {
+		if err == iofs.SkipDir && t.d.IsDir() {
+			// Successfully skipped directory
+			err = nil
+		}
+		return err
+	}
+
+	infos, err := readDir(c.fs, path, c.opts.Unsorted)
+	if err != nil {
+		// Second call, to report ReadDir error
+		err = fn(path, t.d, err)
+		if err != nil {
+			if err == iofs.SkipDir && t.d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	ancestors = c.descendants(ancestors, t)
+	for _, info := range infos {
+		name := joinPath(c.fs, path, info.Name())
+		if err := c.walkDir(name, c.prepare(name, info, ancestors), fn, ancestors); err != nil {
+			if err == iofs.SkipDir {
+				break
 			}
+			return err
 		}
 	}
 	return nil
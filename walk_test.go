@@ -2,10 +2,12 @@ package vfs
 
 import (
 	"errors"
+	iofs "io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -266,14 +268,20 @@ func TestWalkSkipDirOnFile(t *testing.T) {
 type statWrapper struct {
 	Filesystem
 
-	statErr error
+	readDirErr error
+	lstats     int
 }
 
 func (s *statWrapper) Lstat(path string) (os.FileInfo, error) {
-	if strings.HasSuffix(path, "stat-error") {
-		return nil, s.statErr
+	s.lstats++
+	return s.Filesystem.Lstat(path)
+}
+
+func (s *statWrapper) ReadDir(path string) ([]os.FileInfo, error) {
+	if strings.HasSuffix(path, "readdir-error") {
+		return nil, s.readDirErr
 	}
-	return os.Lstat(path)
+	return s.Filesystem.ReadDir(path)
 }
 
 func TestWalkFileError(t *testing.T) {
@@ -288,17 +296,17 @@ func TestWalkFileError(t *testing.T) {
 	touch(t, fs, filepath.Join(td, "foo"))
 	touch(t, fs, filepath.Join(td, "bar"))
 	dir := filepath.Join(td, "dir")
-	if err := MkdirAll(fs, filepath.Join(td, "dir"), 0755); err != nil {
+	if err := MkdirAll(fs, filepath.Join(dir, "readdir-error"), 0755); err != nil {
 		t.Fatal(err)
 	}
 	touch(t, fs, filepath.Join(dir, "baz"))
-	touch(t, fs, filepath.Join(dir, "stat-error"))
-	statErr := errors.New("some stat error")
+	touch(t, fs, filepath.Join(dir, "readdir-error", "hidden"))
+	readDirErr := errors.New("some readdir error")
 
-	fs = &statWrapper{Filesystem: fs, statErr: statErr}
+	sw := &statWrapper{Filesystem: fs, readDirErr: readDirErr}
 
 	got := map[string]error{}
-	err = Walk(fs, td, func(path string, fi os.FileInfo, err error) error {
+	err = Walk(sw, td, func(path string, fi os.FileInfo, err error) error {
 		rel, _ := filepath.Rel(td, path)
 		got[filepath.ToSlash(rel)] = err
 		return nil
@@ -307,14 +315,294 @@ func TestWalkFileError(t *testing.T) {
 		t.Errorf("Walk error: %v", err)
 	}
 	want := map[string]error{
-		".":              nil,
-		"foo":            nil,
-		"bar":            nil,
-		"dir":            nil,
-		"dir/baz":        nil,
-		"dir/stat-error": statErr,
+		".":                 nil,
+		"foo":               nil,
+		"bar":               nil,
+		"dir":               nil,
+		"dir/baz":           nil,
+		"dir/readdir-error": readDirErr,
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("Walked %#v; want %#v", got, want)
 	}
+	// Entries are not stat'ed again
+	if sw.lstats != 1 {
+		t.Errorf("Expected 1 Lstat, got %d", sw.lstats)
+	}
+}
+
+// reverseFS returns the entries of directories in reverse order.
+type reverseFS struct {
+	Filesystem
+}
+
+func (fs reverseFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() > fis[j].Name() })
+	return fis, err
+}
+
+func TestWalkDir(t *testing.T) {
+	td := t.TempDir()
+	fs := Filesystem(OS())
+	walkTree(tree, filepath.Join(td, tree.name), func(path string, n *Node) {
+		if n.entries == nil {
+			touch(t, fs, path)
+		} else {
+			fs.Mkdir(path, 0770)
+		}
+	})
+	root := filepath.Join(td, tree.name)
+
+	sw := &statWrapper{Filesystem: fs}
+	err := WalkDir(sw, root, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		walkTree(tree, tree.name, func(_ string, n *Node) {
+			if n.name == d.Name() {
+				n.mark++
+				if d.IsDir() != (n.entries != nil) {
+					t.Errorf("Unexpected type of %s", path)
+				}
+			}
+		})
+		if info, err := d.Info(); err != nil || info.Name() != d.Name() {
+			t.Errorf("Info: %v, %v", info, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("no error expected, found: %s", err)
+	}
+	checkMarks(t, true)
+	if sw.lstats != 1 {
+		t.Errorf("Expected 1 Lstat, got %d", sw.lstats)
+	}
+
+	walked := func(fs Filesystem, opts WalkOptions, skip string) []string {
+		var paths []string
+		err := WalkDirWithOptions(fs, root, func(path string, d iofs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(root, path)
+			paths = append(paths, filepath.ToSlash(rel))
+			switch {
+			case rel == skip && skip == "x":
+				return iofs.SkipAll
+			case rel == skip || strings.HasSuffix(rel, "/"+skip):
+				return iofs.SkipDir
+			}
+			return nil
+		}, opts)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		return paths
+	}
+	if got, want := walked(fs, WalkOptions{}, "d"), []string{".", "a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SkipDir on directory: %q, want %q", got, want)
+	}
+	if got, want := walked(fs, WalkOptions{}, "x"), []string{".", "a", "b", "c", "d", "d/x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SkipAll: %q, want %q", got, want)
+	}
+	if got, want := walked(fs, WalkOptions{}, "u"), []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SkipDir on file: %q, want %q", got, want)
+	}
+	if got, want := walked(reverseFS{fs}, WalkOptions{}, ""), walked(fs, WalkOptions{}, ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("Not sorted: %q, want %q", got, want)
+	}
+	if got, want := walked(reverseFS{fs}, WalkOptions{Unsorted: true}, "d"), []string{".", "d", "c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unsorted: %q, want %q", got, want)
+	}
+}
+
+func TestWalkDirError(t *testing.T) {
+	td := t.TempDir()
+	fs := Filesystem(OS())
+	if err := MkdirAll(fs, filepath.Join(td, "readdir-error"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	readDirErr := errors.New("some readdir error")
+	sw := &statWrapper{Filesystem: fs, readDirErr: readDirErr}
+
+	var errs []error
+	err := WalkDir(sw, td, func(path string, d iofs.DirEntry, err error) error {
+		if strings.HasSuffix(path, "readdir-error") {
+			errs = append(errs, err)
+		}
+		return err
+	})
+	if err != readDirErr {
+		t.Errorf("Expected readdir error, got %v", err)
+	}
+	if !reflect.DeepEqual(errs, []error{nil, readDirErr}) {
+		t.Errorf("Unexpected calls: %v", errs)
+	}
+
+	if err := WalkDir(fs, filepath.Join(td, "missing"), func(path string, d iofs.DirEntry, err error) error {
+		if d != nil {
+			t.Errorf("Unexpected entry: %v", d)
+		}
+		return err
+	}); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+// sysFS replaces the SysInfo of the FileInfos returned by the filesystem.
+type sysFS struct {
+	Filesystem
+	sys func(path string) *SysInfo
+}
+
+type sysFileInfo struct {
+	os.FileInfo
+	sys *SysInfo
+}
+
+func (fi sysFileInfo) Sys() any {
+	if fi.sys == nil {
+		return nil
+	}
+	return fi.sys
+}
+
+func (fs sysFS) wrap(path string, fi os.FileInfo, err error) (os.FileInfo, error) {
+	if err != nil {
+		return nil, err
+	}
+	return sysFileInfo{FileInfo: fi, sys: fs.sys(path)}, nil
+}
+
+func (fs sysFS) Stat(path string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(path)
+	return fs.wrap(path, fi, err)
+}
+
+func (fs sysFS) Lstat(path string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(path)
+	return fs.wrap(path, fi, err)
+}
+
+func (fs sysFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	for i, fi := range fis {
+		fis[i], _ = fs.wrap(joinPath(fs, path, fi.Name()), fi, nil)
+	}
+	return fis, err
+}
+
+func (fs sysFS) Readlink(path string) (string, error) {
+	return Readlink(fs.Filesystem, path)
+}
+
+// makeLinkTree creates a tree with symbolic links on the OS filesystem.
+func makeLinkTree(t *testing.T) string {
+	root := t.TempDir()
+	fs := OS()
+	if err := MkdirAll(fs, filepath.Join(root, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := MkdirAll(fs, filepath.Join(root, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, fs, filepath.Join(root, "a", "file"))
+	touch(t, fs, filepath.Join(root, "b", "file"))
+	for _, l := range [][2]string{{"..", "a/up"}, {"../b", "a/sib"}, {"missing", "dangling"}, {"b/file", "link"}} {
+		if err := fs.Symlink(l[0], filepath.Join(root, l[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestWalkDirFollowSymlinks(t *testing.T) {
+	root := makeLinkTree(t)
+	walked := func(fs Filesystem, opts WalkOptions) []string {
+		var paths []string
+		err := WalkDirWithOptions(fs, root, func(path string, d iofs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(root, path)
+			rel = filepath.ToSlash(rel)
+			switch {
+			case errors.Is(err, ErrFilesystemLoop):
+				rel += " loop"
+			case err != nil:
+				t.Errorf("Unexpected error: %s", err)
+			case d.Type()&os.ModeSymlink != 0:
+				rel += " link"
+			case d.IsDir():
+				rel += "/"
+			}
+			paths = append(paths, rel)
+			return nil
+		}, opts)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		return paths
+	}
+
+	fs := Filesystem(OS())
+	want := []string{"./", "a/", "a/file", "a/sib link", "a/up link", "b/", "b/file", "dangling link", "link link"}
+	if got := walked(fs, WalkOptions{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Not following: %q, want %q", got, want)
+	}
+	want = []string{"./", "a/", "a/file", "a/sib/", "a/sib/file", "a/up loop", "b/", "b/file", "dangling link", "link"}
+	if got := walked(fs, WalkOptions{FollowSymlinks: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Following: %q, want %q", got, want)
+	}
+
+	// Without inodes the loops are detected by the resolved paths.
+	noSys := sysFS{Filesystem: fs, sys: func(string) *SysInfo { return nil }}
+	if got := walked(noSys, WalkOptions{FollowSymlinks: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Following by path: %q, want %q", got, want)
+	}
+
+	// A link to the root of the walk is a loop.
+	link := filepath.Join(root, "root")
+	if err := fs.Symlink(root, link); err != nil {
+		t.Fatal(err)
+	}
+	var loops []string
+	WalkDirWithOptions(fs, link, func(path string, d iofs.DirEntry, err error) error {
+		if errors.Is(err, ErrFilesystemLoop) {
+			loops = append(loops, path)
+		}
+		return nil
+	}, WalkOptions{FollowSymlinks: true})
+	if want := []string{filepath.Join(link, "a", "up"), filepath.Join(link, "root")}; !reflect.DeepEqual(loops, want) {
+		t.Errorf("Loops: %q, want %q", loops, want)
+	}
+}
+
+func TestWalkDirOneFilesystem(t *testing.T) {
+	root := makeLinkTree(t)
+	fs := sysFS{Filesystem: OS(), sys: func(path string) *SysInfo {
+		if rel, _ := filepath.Rel(root, path); strings.HasPrefix(rel, "b") {
+			return &SysInfo{Dev: 2}
+		}
+		return &SysInfo{Dev: 1}
+	}}
+
+	for _, follow := range []bool{false, true} {
+		var paths []string
+		err := WalkDirWithOptions(fs, root, func(path string, d iofs.DirEntry, err error) error {
+			if err != nil && !errors.Is(err, ErrFilesystemLoop) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			rel, _ := filepath.Rel(root, path)
+			paths = append(paths, filepath.ToSlash(rel))
+			return nil
+		}, WalkOptions{OneFilesystem: true, FollowSymlinks: follow})
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		want := []string{".", "a", "a/file", "a/sib", "a/up", "b", "dangling", "link"}
+		if follow {
+			// The devices are assigned by path, so a/sib is on the device of the root.
+			want = []string{".", "a", "a/file", "a/sib", "a/sib/file", "a/up", "b", "dangling", "link"}
+		}
+		if !reflect.DeepEqual(paths, want) {
+			t.Errorf("Follow %v: %q, want %q", follow, paths, want)
+		}
+	}
 }